@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"../../internal/user"
+	"github.com/go-chi/chi"
+)
+
+// adminUser is the user as admins see it
+type adminUser struct {
+	ID            int64     `json:"id"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Locked        bool      `json:"locked"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func userLocked(w http.ResponseWriter) {
+	http.Error(w, "{\"error\": \"user is locked\"}", http.StatusForbidden)
+}
+
+// AdminGetUsers lists users whose email or name contains the q query parameter
+func (h *Handler) AdminGetUsers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	users, err := h.userStorage.Search(r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		h.logger.Errorf("Can't search users: %s", err)
+		http.Error(w, "{\"error\": \"can't get users\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	result := make([]adminUser, 0, len(users))
+	for _, u := range users {
+		result = append(result, adminUser{
+			ID:            u.ID,
+			FirstName:     u.FirstName,
+			LastName:      u.LastName,
+			Email:         u.Email,
+			EmailVerified: u.EmailVerified,
+			Role:          u.Role,
+			Locked:        u.Locked,
+			CreatedAt:     u.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		h.logger.Errorf("Can't encode users: %s", err)
+	}
+}
+
+// AdminLockUser locks the user out and ends all the user sessions
+func (h *Handler) AdminLockUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if id == claimsFromContext(r.Context()).UserID {
+		http.Error(w, "{\"error\": \"can't lock yourself\"}", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userStorage.SetLocked(id, true); err != nil {
+		h.logger.Errorf("Can't lock user: %s", err)
+		http.Error(w, "{\"error\": \"can't lock user\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	h.revokeSessions(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) AdminUnlockUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userStorage.SetLocked(id, false); err != nil {
+		h.logger.Errorf("Can't unlock user: %s", err)
+		http.Error(w, "{\"error\": \"can't unlock user\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminSetRole changes the role of the user, it applies to issued access tokens at once
+func (h *Handler) AdminSetRole(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Role string `json:"role"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || user.CheckRole(req.Role) != nil {
+		http.Error(w, "{\"error\": \"wrong role\"}", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userStorage.SetRole(id, req.Role); err != nil {
+		h.logger.Errorf("Can't set role: %s", err)
+		http.Error(w, "{\"error\": \"can't set role\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminDeleteSessions logs the user out on all devices
+func (h *Handler) AdminDeleteSessions(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.adminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	h.revokeSessions(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// adminTargetUser reads the {id} route parameter of the existing user
+func (h *Handler) adminTargetUser(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return 0, false
+	}
+
+	if _, err = h.userStorage.FindByID(id); err != nil {
+		h.logger.Errorf("Can't get user: %s", err)
+		http.Error(w, "{\"error\": \"can't get user\"}", http.StatusNotFound)
+
+		return 0, false
+	}
+
+	return id, true
+}
+
+// AdminDeleteRobot deletes the robot of any user stopping its trading
+func (h *Handler) AdminDeleteRobot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	robotData, err := h.robotStorage.FindByID(id)
+	if err != nil {
+		h.logger.Errorf("Can't get robot: %s", err)
+		http.Error(w, "{\"error\": \"can't get robot\"}", http.StatusNotFound)
+
+		return
+	}
+
+	if robotData.DeletedAt.Valid {
+		http.Error(w, "{\"error\": \"robot is already deleted\"}", http.StatusBadRequest)
+		return
+	}
+
+	if err = h.robotStorage.DeleteByID(id); err != nil {
+		h.logger.Errorf("Can't delete robot: %s", err)
+		http.Error(w, "{\"error\": \"error while deleting robot\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	h.release(id)
+
+	h.schedule(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AdminRestoreRobot brings the deleted robot back deactivated, its capital has been
+// released on deletion so the owner has to activate it again
+func (h *Handler) AdminRestoreRobot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	robotData, err := h.robotStorage.FindByID(id)
+	if err != nil {
+		h.logger.Errorf("Can't get robot: %s", err)
+		http.Error(w, "{\"error\": \"can't get robot\"}", http.StatusNotFound)
+
+		return
+	}
+
+	if !robotData.DeletedAt.Valid {
+		http.Error(w, "{\"error\": \"robot is not deleted\"}", http.StatusBadRequest)
+		return
+	}
+
+	if robotData.IsActive {
+		if err = h.robotStorage.DeactivateByID(id); err != nil {
+			h.logger.Errorf("Can't deactivate robot: %s", err)
+			http.Error(w, "{\"error\": \"error while restoring robot\"}", http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	if err = h.robotStorage.RestoreByID(id); err != nil {
+		h.logger.Errorf("Can't restore robot: %s", err)
+		http.Error(w, "{\"error\": \"error while restoring robot\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	h.schedule(id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"../../internal/session"
+	"github.com/go-chi/chi"
+)
+
+type contextKey int
+
+const claimsKey contextKey = iota
+
+// authenticate verifies the access token of the Authorization header and puts its claims into
+// the request context, requests without a valid token get 401. The user is loaded on every
+// request so locking and role changes apply at once instead of when the token expires
+func (h *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" {
+			unauthorized(w)
+			return
+		}
+
+		claims, err := h.signer.Verify(token)
+		if err != nil {
+			h.logger.Infof("Unvalid token: %v", err)
+			unauthorized(w)
+
+			return
+		}
+
+		u, err := h.userStorage.FindByID(claims.UserID)
+		if err != nil {
+			h.logger.Infof("Can't find user of token: %v", err)
+			unauthorized(w)
+
+			return
+		}
+
+		if u.Locked {
+			userLocked(w)
+			return
+		}
+
+		claims.Role = u.Role
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
+	})
+}
+
+// userOwner lets only the user of the {id} route parameter in, it must follow authenticate
+func (h *Handler) userOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+			return
+		}
+
+		if claimsFromContext(r.Context()).UserID != id {
+			forbidden(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// robotOwner lets only the owner of the robot of the {id} route parameter in, deleted robots
+// are not found, it must follow authenticate
+func (h *Handler) robotOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+			return
+		}
+
+		robotData, err := h.robotStorage.FindByID(id)
+		if err != nil || robotData.DeletedAt.Valid {
+			h.logger.Errorf("Can't get robot %d: %v", id, err)
+			http.Error(w, "{\"error\": \"can't get robot\"}", http.StatusNotFound)
+
+			return
+		}
+
+		if claimsFromContext(r.Context()).UserID != robotData.OwnerUserID {
+			forbidden(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireRole lets only users of the role in, it must follow authenticate
+func (h *Handler) requireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if claimsFromContext(r.Context()).Role != role {
+				forbidden(w)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// claimsFromContext returns the access token claims put by authenticate
+func claimsFromContext(ctx context.Context) *session.Claims {
+	claims, _ := ctx.Value(claimsKey).(*session.Claims)
+	return claims
+}
+
+func unauthorized(w http.ResponseWriter) {
+	http.Error(w, "{\"error\": \"unauthorized\"}", http.StatusUnauthorized)
+}
+
+func forbidden(w http.ResponseWriter) {
+	http.Error(w, "{\"error\": \"forbidden\"}", http.StatusForbidden)
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"../../internal/mail"
+	"../../internal/session"
+	"../../internal/user"
+	"github.com/pkg/errors"
+)
+
+// Lifetime of tokens sent by email
+const (
+	verifyEmailTTL   = 24 * time.Hour
+	resetPasswordTTL = time.Hour
+)
+
+// sendVerification mails the link verifying the email of the user in the background
+func (h *Handler) sendVerification(u user.User) {
+	h.sendMail(func() {
+		token, err := h.createToken(u.ID, user.VerifyEmail, verifyEmailTTL)
+		if err != nil {
+			h.logger.Errorf("Can't create verification token: %s", err)
+			return
+		}
+
+		link := fmt.Sprintf("%s/api/v1/email/verify?token=%s", h.publicURL, url.QueryEscape(token))
+
+		err = h.mailer.Send(mail.Message{
+			To:      u.Email,
+			Subject: "Confirm your email",
+			Body:    fmt.Sprintf("Hello, %s!\n\nOpen the link to confirm your email:\n%s\n", u.FirstName, link),
+		})
+		if err != nil {
+			h.logger.Errorf("Can't send verification: %s", err)
+		}
+	})
+}
+
+// sendMail runs send after the response, a slow mail server holds no request
+func (h *Handler) sendMail(send func()) {
+	h.mails.Add(1)
+
+	go func() {
+		defer h.mails.Done()
+		send()
+	}()
+}
+
+func (h *Handler) createToken(userID int64, purpose string, ttl time.Duration) (string, error) {
+	token, err := session.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	t := user.Token{Token: token, UserID: userID, Purpose: purpose, ValidUntil: time.Now().Add(ttl)}
+
+	if err = h.userStorage.CreateToken(&t); err != nil {
+		return "", errors.Wrap(err, "can't save token")
+	}
+
+	return token, nil
+}
+
+// VerifyEmail handles the link sent by email
+func (h *Handler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	t, err := h.userStorage.UseToken(r.URL.Query().Get("token"), user.VerifyEmail)
+	if err != nil {
+		h.logger.Infof("Can't verify email: %s", err)
+		http.Error(w, "{\"error\": \"wrong or expired token\"}", http.StatusBadRequest)
+
+		return
+	}
+
+	if err = h.userStorage.SetEmailVerified(t.UserID); err != nil {
+		h.logger.Errorf("Can't verify email: %s", err)
+		http.Error(w, "{\"error\": \"can't verify email\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(struct {
+		EmailVerified bool `json:"email_verified"`
+	}{true}); err != nil {
+		http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
+		return
+	}
+}
+
+// ResendVerification mails a new verification link to the user
+func (h *Handler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	u, err := h.userStorage.FindByID(claimsFromContext(r.Context()).UserID)
+	if err != nil {
+		http.Error(w, "{\"error\": \"could not find\"}", http.StatusNotFound)
+		return
+	}
+
+	if u.EmailVerified {
+		http.Error(w, "{\"error\": \"email is already verified\"}", http.StatusBadRequest)
+		return
+	}
+
+	h.sendVerification(*u)
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+type forgotRequest struct {
+	Email string `json:"email"`
+}
+
+// ForgotPassword mails the password reset token in the background, so neither
+// the response nor its time shows whether the user exists
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "{\"error\": \"wrong input data\"}", http.StatusBadRequest)
+		return
+	}
+
+	h.sendMail(func() {
+		h.sendResetToken(req.Email)
+	})
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// WaitMails blocks until the mails sent in the background are done
+func (h *Handler) WaitMails() {
+	h.mails.Wait()
+}
+
+func (h *Handler) sendResetToken(email string) {
+	u, err := h.userStorage.FindByEmail(email)
+	if err != nil {
+		h.logger.Infof("Password reset of unknown user: %s", err)
+		return
+	}
+
+	token, err := h.createToken(u.ID, user.ResetPassword, resetPasswordTTL)
+	if err != nil {
+		h.logger.Errorf("Can't create reset token: %s", err)
+		return
+	}
+
+	err = h.mailer.Send(mail.Message{
+		To:      u.Email,
+		Subject: "Password reset",
+		Body: fmt.Sprintf("Hello, %s!\n\nSend the token to POST %s/api/v1/password/reset within %v "+
+			"to set a new password:\n%s\n\nIgnore the message if you haven't asked for it.\n",
+			u.FirstName, h.publicURL, resetPasswordTTL, token),
+	})
+	if err != nil {
+		h.logger.Errorf("Can't send reset token: %s", err)
+	}
+}
+
+type resetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// ResetPassword sets the new password and revokes all sessions of the user,
+// the token proves the email too
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "{\"error\": \"wrong input data\"}", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.userStorage.UseToken(req.Token, user.ResetPassword)
+	if err != nil {
+		h.logger.Infof("Can't reset password: %s", err)
+		http.Error(w, "{\"error\": \"wrong or expired token\"}", http.StatusBadRequest)
+
+		return
+	}
+
+	hash, err := user.HashPassword(req.Password)
+	if err != nil {
+		http.Error(w, "{\"error\": \"can't hash password\"}", http.StatusInternalServerError)
+		return
+	}
+
+	if err = h.userStorage.UpdatePassword(t.UserID, hash); err != nil {
+		h.logger.Errorf("Can't update password: %s", err)
+		http.Error(w, "{\"error\": \"can't update password\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	if err = h.userStorage.SetEmailVerified(t.UserID); err != nil {
+		h.logger.Errorf("Can't verify email: %s", err)
+	}
+
+	h.revokeSessions(t.UserID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// revokeSessions logs the user out on all devices
+func (h *Handler) revokeSessions(userID int64) {
+	sessions, err := h.sessionStorage.FindByUserID(userID)
+	if err != nil {
+		h.logger.Errorf("Can't get sessions: %s", err)
+		return
+	}
+
+	for _, sess := range sessions {
+		if err = h.sessionStorage.DeleteFamily(sess.Family); err != nil {
+			h.logger.Errorf("Can't revoke session: %s", err)
+		}
+	}
+}
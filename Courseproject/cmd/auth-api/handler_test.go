@@ -4,14 +4,22 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
 	"testing"
 	"time"
 
 	"../../internal/database"
-	"../../internal/session"
+	"../../internal/mail"
+	"../../internal/robot"
+	"../../internal/user"
 	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -22,49 +30,37 @@ type testCase struct {
 	Code    int
 }
 
+var testAuth = AuthConfig{TokenKey: "secret", AccessTTL: time.Minute, RefreshTTL: time.Hour, Sliding: true}
+
 func NewTestServer() (*httptest.Server, error) {
-	r := chi.NewRouter()
+	ts, _, _, err := newTestServer()
+	return ts, err
+}
 
+// newTestServer returns the handler and the outbox of the test server too
+func newTestServer() (*httptest.Server, *Handler, *mail.Outbox, error) {
 	logger, err := zap.NewDevelopment()
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 
 	userStorage := database.NewUserStorage()
 	sessionStorage := database.NewSessionStorage()
 	robotStorage := database.NewRobotStorage()
+	dealStorage := database.NewDealStorage(database.NewOrderStorage())
+	accountStorage := database.NewAccountStorage()
 
-	h, err := NewHandler(logger, userStorage, sessionStorage, robotStorage)
+	outbox, err := mail.NewOutbox("")
 	if err != nil {
-		logger.Sugar().Fatalf("Can't create server: %s", err)
+		return nil, nil, nil, err
 	}
 
-	r.Route("/api/v1", func(r chi.Router) {
-		r.Post("/signup", h.PostSignup)
-		r.Post("/signin", h.PostSignin)
-		r.Route("/users/{id}", func(r chi.Router) {
-			r.Put("/", h.PutUser)
-			r.Get("/", h.GetUser)
-			// r.Get("/robots", h.GetUserRobots)
-		})
-		r.Route("/robot", func(r chi.Router) {
-			r.Post("/", h.CreateRobot)
-			// r.Route("/{id}", func(r chi.Router) {
-			// 		r.Delete("/", h.DeleteRobotByID)
-			// 		// r.Put("/", h.UpdateRobotByID)
-			// r.Get("/", h.GetRobotDetails)
-			// 		r.Put("/favorite", h.AddRobotToFavorite)
-			// 		r.Put("/activate", h.ActivateRobot)
-			// 		r.Put("/deactivate", h.DeactivateRobot)
-			// })
-			// 	r.HandleFunc("/robots_ws", h.WSRobotUpdate)
-		})
-		// r.Route("/robots", func(r chi.Router) {
-		// 	r.Get("/", h.GetRobots)
-		// })
-	})
+	h, err := NewHandler(logger, userStorage, sessionStorage, robotStorage, dealStorage, accountStorage, testAuth, outbox)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create server: %s", err)
+	}
 
-	return httptest.NewServer(r), nil
+	return httptest.NewServer(h.NewRouter()), h, outbox, nil
 }
 
 func TestHandler_PostSignUp(t *testing.T) {
@@ -130,13 +126,13 @@ func TestHandler_PutUser(t *testing.T) {
 
 	r.NoError(err)
 
-	var ans session.Session
+	var ans tokenResponse
 
 	err = json.NewDecoder(resp.Body).Decode(&ans)
 
 	r.NoError(err)
 
-	token := ans.SessionID
+	token := ans.AccessToken
 
 	resp.Body.Close()
 
@@ -173,13 +169,13 @@ func TestHandler_GetUser(t *testing.T) {
 
 	r.NoError(err)
 
-	var ans session.Session
+	var ans tokenResponse
 
 	err = json.NewDecoder(resp.Body).Decode(&ans)
 
 	r.NoError(err)
 
-	token := ans.SessionID
+	token := ans.AccessToken
 
 	resp.Body.Close()
 
@@ -195,6 +191,74 @@ func TestHandler_GetUser(t *testing.T) {
 	defer resp.Body.Close()
 }
 
+// nolint: gomnd
+func TestHandler_Deposit(t *testing.T) {
+	tc := testCase{
+		Request: `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`,
+		Accept:  "application/json",
+		Code:    http.StatusOK,
+	}
+
+	r := require.New(t)
+
+	ts, err := NewTestServer()
+	r.NoError(err)
+
+	client := http.Client{Timeout: time.Second}
+	resp, _ := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), tc.Accept, bytes.NewBuffer([]byte(tc.Request)))
+	resp.Body.Close()
+
+	resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), tc.Accept, bytes.NewBuffer([]byte(tc.Request)))
+	r.NoError(err)
+
+	var ans tokenResponse
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&ans))
+	resp.Body.Close()
+
+	post := func(path, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/users/%d/%s", ts.URL, ans.UserID, path), bytes.NewBufferString(body))
+		r.NoError(err)
+		req.Header.Add("Authorization", ans.AccessToken)
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+
+		return resp
+	}
+
+	resp = post("deposit", `{"amount": 100}`)
+	r.Equal(tc.Code, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = post("withdraw", `{"amount": 150}`)
+	r.Equal(http.StatusBadRequest, resp.StatusCode, "insufficient funds")
+	resp.Body.Close()
+
+	resp = post("withdraw", `{"amount": -1}`)
+	r.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+
+	resp = post("withdraw", `{"amount": 30}`)
+	r.Equal(tc.Code, resp.StatusCode)
+	resp.Body.Close()
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/users/%d", ts.URL, ans.UserID), nil)
+	r.NoError(err)
+	req.Header.Add("Authorization", ans.AccessToken)
+
+	resp, err = client.Do(req)
+	r.NoError(err)
+
+	defer resp.Body.Close()
+
+	var u user.ShortUser
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&u))
+	r.NotNil(u.Account)
+	r.InDelta(70, u.Account.Balance, 1e-9)
+}
+
 func Test_CreateRobot(t *testing.T) {
 	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
 	tc := testCase{
@@ -216,13 +280,13 @@ func Test_CreateRobot(t *testing.T) {
 
 	r.NoError(err)
 
-	var ans session.Session
+	var ans tokenResponse
 
 	err = json.NewDecoder(resp.Body).Decode(&ans)
 
 	r.NoError(err)
 
-	token := ans.SessionID
+	token := ans.AccessToken
 
 	resp.Body.Close()
 
@@ -237,3 +301,709 @@ func Test_CreateRobot(t *testing.T) {
 
 	defer resp.Body.Close()
 }
+
+// nolint: gomnd
+func Test_CreateRobotOwner(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, h, _, err := newTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+	resp.Body.Close()
+
+	resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+
+	var ans tokenResponse
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&ans))
+	resp.Body.Close()
+
+	// the owner of the body is ignored, robots belong to the signed in user
+	body := fmt.Sprintf(`{"owner_user_id": %d, "ticker": "AAPL", "buy_price": 1, "sell_price": 2}`, ans.UserID+1)
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/api/v1/robot/", ts.URL), bytes.NewBufferString(body))
+	r.NoError(err)
+	req.Header.Add("Authorization", ans.AccessToken)
+
+	resp, err = client.Do(req)
+	r.NoError(err)
+	resp.Body.Close()
+	r.Equal(http.StatusCreated, resp.StatusCode)
+
+	robots, err := h.robotStorage.GetAllRobotsByOwnerID(ans.UserID)
+	r.NoError(err)
+	r.Len(robots, 1)
+
+	robots, err = h.robotStorage.GetAllRobotsByOwnerID(ans.UserID + 1)
+	r.NoError(err)
+	r.Empty(robots)
+}
+
+func TestHandler_DeactivateRobotInPlan(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, h, _, err := newTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+	resp.Body.Close()
+
+	resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+
+	var ans tokenResponse
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&ans))
+	resp.Body.Close()
+
+	robotData := robot.Robot{OwnerUserID: ans.UserID, Ticker: "AAPL", BuyPrice: 1, SellPrice: 2, IsActive: true,
+		PlanStart: time.Now().Add(-time.Hour), PlanEnd: time.Now().Add(time.Hour)}
+	r.NoError(h.robotStorage.Create(&robotData))
+
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/v1/robot/%d/deactivate", ts.URL, robotData.RobotID), nil)
+	r.NoError(err)
+	req.Header.Add("Authorization", ans.AccessToken)
+
+	resp, err = client.Do(req)
+	r.NoError(err)
+	resp.Body.Close()
+	r.Equal(http.StatusBadRequest, resp.StatusCode)
+
+	stored, err := h.robotStorage.FindByID(robotData.RobotID)
+	r.NoError(err)
+	r.True(stored.IsActive)
+}
+
+// nolint: gomnd
+func TestHandler_Capital(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, h, _, err := newTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+	resp.Body.Close()
+
+	resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+
+	var ans tokenResponse
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&ans))
+	resp.Body.Close()
+	r.NoError(h.userStorage.SetEmailVerified(ans.UserID))
+
+	do := func(method, path string) int {
+		req, err := http.NewRequest(method, ts.URL+path, nil)
+		r.NoError(err)
+		req.Header.Add("Authorization", ans.AccessToken)
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	balance := func() (float64, float64) {
+		a, err := h.accountStorage.FindByUserID(ans.UserID)
+		r.NoError(err)
+
+		return a.Balance, a.Reserved
+	}
+
+	robotData := robot.Robot{OwnerUserID: ans.UserID, Ticker: "AAPL", BuyPrice: 10, SellPrice: 11,
+		PlanStart: time.Now().Add(time.Hour), PlanEnd: time.Now().Add(2 * time.Hour)}
+	r.NoError(h.robotStorage.Create(&robotData))
+
+	path := fmt.Sprintf("/api/v1/robot/%d", robotData.RobotID)
+
+	r.NoError(h.accountStorage.Deposit(ans.UserID, 5))
+	r.Equal(http.StatusBadRequest, do(http.MethodPut, path+"/activate"), "insufficient funds")
+
+	stored, err := h.robotStorage.FindByID(robotData.RobotID)
+	r.NoError(err)
+	r.False(stored.IsActive)
+
+	r.NoError(h.accountStorage.Deposit(ans.UserID, 20))
+	r.Equal(http.StatusOK, do(http.MethodPut, path+"/activate"))
+
+	b, reserved := balance()
+	r.Equal(15.0, b)
+	r.Equal(10.0, reserved)
+
+	r.Equal(http.StatusOK, do(http.MethodPut, path+"/deactivate"))
+
+	b, reserved = balance()
+	r.Equal(25.0, b)
+	r.Zero(reserved)
+
+	// the capital of an open position stays reserved after deletion
+	r.Equal(http.StatusOK, do(http.MethodPut, path+"/activate"))
+	r.NoError(h.robotStorage.UpdatePositionByID(robotData.RobotID, &robot.Position{Side: robot.Bought, Quantity: 1, EntryPrice: 10}))
+	r.Equal(http.StatusOK, do(http.MethodDelete, path))
+
+	b, reserved = balance()
+	r.Equal(15.0, b)
+	r.Equal(10.0, reserved)
+}
+
+func TestHandler_Metrics(t *testing.T) {
+	r := require.New(t)
+
+	router := chi.NewRouter()
+	router.Use(instrument)
+	router.Handle("/metrics", promhttp.Handler())
+	router.Get("/robot/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	// the metrics are global, so only their growth is checked
+	requests := httpRequestsTotal.WithLabelValues(http.MethodGet, "/robot/{id}", "404")
+	requestsBefore := testutil.ToFloat64(requests)
+	durationsBefore := histogramCount(t, http.MethodGet, "/robot/{id}")
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(fmt.Sprintf("%s/robot/%d", ts.URL, i))
+		r.NoError(err)
+		r.Equal(http.StatusNotFound, resp.StatusCode)
+		resp.Body.Close()
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s/metrics", ts.URL))
+	r.NoError(err)
+
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	r.NoError(err)
+	r.Contains(string(body), `fintech_api_http_requests_total{code="404",method="GET",route="/robot/{id}"}`)
+	r.Contains(string(body), `fintech_api_http_request_duration_seconds_count{method="GET",route="/robot/{id}"}`)
+
+	r.Equal(requestsBefore+2, testutil.ToFloat64(requests))
+	r.Equal(durationsBefore+2, histogramCount(t, http.MethodGet, "/robot/{id}"))
+}
+
+func histogramCount(t *testing.T, method, route string) uint64 {
+	var m dto.Metric
+
+	require.NoError(t, httpRequestDuration.WithLabelValues(method, route).(prometheus.Histogram).Write(&m))
+
+	return m.GetHistogram().GetSampleCount()
+}
+
+// nolint: gomnd
+func TestHandler_Auth(t *testing.T) {
+	r := require.New(t)
+
+	ts, err := NewTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	signin := func(email string) tokenResponse {
+		u := fmt.Sprintf(`{"first_name": "Golang","last_name": "Developer", "email": "%s","password": "password"}`, email)
+
+		resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+		r.NoError(err)
+		resp.Body.Close()
+
+		resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u))
+		r.NoError(err)
+
+		defer resp.Body.Close()
+
+		var tokens tokenResponse
+
+		r.NoError(json.NewDecoder(resp.Body).Decode(&tokens))
+
+		return tokens
+	}
+
+	owner := signin("owner@tinkoff.ru")
+	other := signin("other@tinkoff.ru")
+
+	do := func(method, path, token string) int {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(`{"amount": 10}`))
+		r.NoError(err)
+
+		if token != "" {
+			req.Header.Add("Authorization", token)
+		}
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+		resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	deposit := fmt.Sprintf("/api/v1/users/%d/deposit", owner.UserID)
+
+	r.Equal(http.StatusUnauthorized, do(http.MethodGet, "/api/v1/robots/", ""))
+	r.Equal(http.StatusUnauthorized, do(http.MethodPost, deposit, ""))
+	r.Equal(http.StatusUnauthorized, do(http.MethodPost, deposit, "wrong"))
+	r.Equal(http.StatusForbidden, do(http.MethodPost, deposit, other.AccessToken))
+	r.Equal(http.StatusOK, do(http.MethodPost, deposit, owner.AccessToken))
+	r.Equal(http.StatusOK, do(http.MethodPost, deposit, "Bearer "+owner.AccessToken))
+	r.Equal(http.StatusOK, do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d", owner.UserID), other.AccessToken))
+}
+
+// nolint: gomnd
+func TestHandler_RefreshToken(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, err := NewTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+	resp.Body.Close()
+
+	resp, err = client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+
+	var first tokenResponse
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&first))
+	resp.Body.Close()
+	r.Equal("Bearer", first.TokenType)
+	r.Equal(int64(60), first.ExpiresIn)
+
+	refresh := func(token string) (tokenResponse, int) {
+		resp, err := client.Post(fmt.Sprintf("%s/api/v1/token/refresh", ts.URL), "application/json",
+			bytes.NewBufferString(fmt.Sprintf(`{"refresh_token": "%s"}`, token)))
+		r.NoError(err)
+
+		defer resp.Body.Close()
+
+		var tokens tokenResponse
+
+		if resp.StatusCode == http.StatusOK {
+			r.NoError(json.NewDecoder(resp.Body).Decode(&tokens))
+		}
+
+		return tokens, resp.StatusCode
+	}
+
+	second, code := refresh(first.RefreshToken)
+	r.Equal(http.StatusOK, code)
+	r.Equal(first.UserID, second.UserID)
+	r.NotEqual(first.RefreshToken, second.RefreshToken)
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/users/%d", ts.URL, second.UserID), nil)
+	r.NoError(err)
+	req.Header.Add("Authorization", "Bearer "+second.AccessToken)
+
+	resp, err = client.Do(req)
+	r.NoError(err)
+	resp.Body.Close()
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	_, code = refresh(first.RefreshToken)
+	r.Equal(http.StatusUnauthorized, code, "reused token")
+
+	_, code = refresh(second.RefreshToken)
+	r.Equal(http.StatusUnauthorized, code, "family is revoked after reuse")
+
+	_, code = refresh("unknown")
+	r.Equal(http.StatusUnauthorized, code)
+}
+
+// nolint: gomnd
+func TestHandler_Sessions(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, err := NewTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+	r.NoError(err)
+	resp.Body.Close()
+
+	do := func(method, path, token, userAgent, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		r.NoError(err)
+		req.Header.Set("User-Agent", userAgent)
+
+		if token != "" {
+			req.Header.Add("Authorization", "Bearer "+token)
+		}
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+
+		return resp
+	}
+
+	signin := func(userAgent string) tokenResponse {
+		resp := do(http.MethodPost, "/api/v1/signin", "", userAgent, u)
+		defer resp.Body.Close()
+
+		var tokens tokenResponse
+
+		r.NoError(json.NewDecoder(resp.Body).Decode(&tokens))
+
+		return tokens
+	}
+
+	sessions := func(tokens tokenResponse) []sessionInfo {
+		resp := do(http.MethodGet, fmt.Sprintf("/api/v1/users/%d/sessions", tokens.UserID), tokens.AccessToken, "", "")
+		defer resp.Body.Close()
+
+		r.Equal(http.StatusOK, resp.StatusCode)
+
+		var result []sessionInfo
+
+		r.NoError(json.NewDecoder(resp.Body).Decode(&result))
+
+		return result
+	}
+
+	status := func(resp *http.Response) int {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	laptop := signin("laptop")
+	phone := signin("phone")
+
+	list := sessions(laptop)
+	r.Len(list, 2)
+	r.Equal("laptop", list[0].UserAgent)
+	r.True(list[0].Current)
+	r.Equal("phone", list[1].UserAgent)
+	r.False(list[1].Current)
+
+	r.Equal(http.StatusNotFound, status(do(http.MethodDelete, "/api/v1/sessions/unknown", phone.AccessToken, "phone", "")))
+	r.Equal(http.StatusNoContent, status(do(http.MethodDelete, "/api/v1/sessions/"+list[0].ID, phone.AccessToken, "phone", "")))
+
+	refresh := fmt.Sprintf(`{"refresh_token": "%s"}`, laptop.RefreshToken)
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodPost, "/api/v1/token/refresh", "", "laptop", refresh)))
+	r.Len(sessions(phone), 1)
+
+	r.Equal(http.StatusNoContent, status(do(http.MethodPost, "/api/v1/logout", phone.AccessToken, "phone", "")))
+	r.Len(sessions(phone), 0)
+
+	refresh = fmt.Sprintf(`{"refresh_token": "%s"}`, phone.RefreshToken)
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodPost, "/api/v1/token/refresh", "", "phone", refresh)))
+}
+
+// nolint: gomnd
+func TestHandler_SessionLifetime(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+
+	for _, sliding := range []bool{true, false} {
+		r := require.New(t)
+
+		ts, h, _, err := newTestServer()
+		r.NoError(err)
+
+		h.sliding = sliding
+
+		client := http.Client{Timeout: time.Second}
+		resp, err := client.Post(fmt.Sprintf("%s/api/v1/signup", ts.URL), "application/json", bytes.NewBufferString(u))
+		r.NoError(err)
+		resp.Body.Close()
+
+		tokens := func(resp *http.Response, err error) tokenResponse {
+			r.NoError(err)
+
+			defer resp.Body.Close()
+
+			r.Equal(http.StatusOK, resp.StatusCode)
+
+			var result tokenResponse
+
+			r.NoError(json.NewDecoder(resp.Body).Decode(&result))
+
+			return result
+		}
+
+		validUntil := func(tokens tokenResponse) time.Time {
+			req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/api/v1/users/%d/sessions", ts.URL, tokens.UserID), nil)
+			r.NoError(err)
+			req.Header.Add("Authorization", "Bearer "+tokens.AccessToken)
+
+			resp, err := client.Do(req)
+			r.NoError(err)
+
+			defer resp.Body.Close()
+
+			var result []sessionInfo
+
+			r.NoError(json.NewDecoder(resp.Body).Decode(&result))
+			r.Len(result, 1)
+
+			return result[0].ValidUntil
+		}
+
+		first := tokens(client.Post(fmt.Sprintf("%s/api/v1/signin", ts.URL), "application/json", bytes.NewBufferString(u)))
+		before := validUntil(first)
+
+		time.Sleep(10 * time.Millisecond)
+
+		second := tokens(client.Post(fmt.Sprintf("%s/api/v1/token/refresh", ts.URL), "application/json",
+			bytes.NewBufferString(fmt.Sprintf(`{"refresh_token": "%s"}`, first.RefreshToken))))
+		after := validUntil(second)
+
+		if sliding {
+			r.True(after.After(before), "sliding session is extended on refresh")
+		} else {
+			r.True(after.Equal(before), "session lives RefreshTTL since sign in")
+		}
+
+		ts.Close()
+	}
+}
+
+// nolint: gomnd
+func TestHandler_EmailVerification(t *testing.T) {
+	u := `{"first_name": "Golang","last_name": "Developer", "email": "go_dev@tinkoff.ru","password": "password"}`
+	r := require.New(t)
+
+	ts, h, outbox, err := newTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	do := func(method, path, token, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		r.NoError(err)
+
+		if token != "" {
+			req.Header.Add("Authorization", token)
+		}
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+
+		return resp
+	}
+
+	status := func(resp *http.Response) int {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	signin := func(password string) (tokenResponse, int) {
+		resp := do(http.MethodPost, "/api/v1/signin", "", fmt.Sprintf(`{"email": "go_dev@tinkoff.ru","password": "%s"}`, password))
+		defer resp.Body.Close()
+
+		var tokens tokenResponse
+
+		if resp.StatusCode == http.StatusOK {
+			r.NoError(json.NewDecoder(resp.Body).Decode(&tokens))
+		}
+
+		return tokens, resp.StatusCode
+	}
+
+	lastToken := func(pattern string) string {
+		messages := outbox.Messages()
+		r.NotEmpty(messages)
+
+		m := regexp.MustCompile(pattern).FindStringSubmatch(messages[len(messages)-1].Body)
+		r.Len(m, 2)
+
+		return m[1]
+	}
+
+	r.Equal(http.StatusCreated, status(do(http.MethodPost, "/api/v1/signup", "", u)))
+	h.WaitMails()
+	r.Len(outbox.Messages(), 1)
+	r.Equal("go_dev@tinkoff.ru", outbox.Messages()[0].To)
+
+	tokens, code := signin("password")
+	r.Equal(http.StatusOK, code)
+
+	robotData := robot.Robot{OwnerUserID: tokens.UserID, Ticker: "AAPL", BuyPrice: 1, SellPrice: 2}
+	r.NoError(h.robotStorage.Create(&robotData))
+
+	activate := fmt.Sprintf("/api/v1/robot/%d/activate", robotData.RobotID)
+	r.Equal(http.StatusForbidden, status(do(http.MethodPut, activate, tokens.AccessToken, "")), "email is not verified")
+
+	verify := lastToken(`verify\?token=(\w+)`)
+	r.Equal(http.StatusBadRequest, status(do(http.MethodGet, "/api/v1/email/verify?token=wrong", "", "")))
+	r.Equal(http.StatusOK, status(do(http.MethodGet, "/api/v1/email/verify?token="+verify, "", "")))
+	r.Equal(http.StatusBadRequest, status(do(http.MethodGet, "/api/v1/email/verify?token="+verify, "", "")), "token is used")
+	r.NotEqual(http.StatusForbidden, status(do(http.MethodPut, activate, tokens.AccessToken, "")))
+
+	r.Equal(http.StatusAccepted, status(do(http.MethodPost, "/api/v1/password/forgot", "", `{"email": "unknown@tinkoff.ru"}`)))
+	h.WaitMails()
+	r.Len(outbox.Messages(), 1)
+	r.Equal(http.StatusAccepted, status(do(http.MethodPost, "/api/v1/password/forgot", "", `{"email": "go_dev@tinkoff.ru"}`)))
+	h.WaitMails()
+	r.Len(outbox.Messages(), 2)
+
+	reset := lastToken(`(?m)^(\w+)$`)
+	r.Equal(http.StatusBadRequest, status(do(http.MethodPost, "/api/v1/password/reset", "", `{"token": "wrong", "password": "new"}`)))
+	r.Equal(http.StatusNoContent, status(do(http.MethodPost, "/api/v1/password/reset", "",
+		fmt.Sprintf(`{"token": "%s", "password": "new"}`, reset))))
+
+	_, code = signin("password")
+	r.Equal(http.StatusBadRequest, code)
+
+	_, code = signin("new")
+	r.Equal(http.StatusOK, code)
+
+	refresh := fmt.Sprintf(`{"refresh_token": "%s"}`, tokens.RefreshToken)
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodPost, "/api/v1/token/refresh", "", refresh)), "sessions are revoked")
+}
+
+func TestHandler_Admin(t *testing.T) {
+	r := require.New(t)
+
+	ts, h, _, err := newTestServer()
+	r.NoError(err)
+
+	defer ts.Close()
+
+	client := http.Client{Timeout: time.Second}
+
+	do := func(method, path, token, body string) *http.Response {
+		req, err := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		r.NoError(err)
+
+		if token != "" {
+			req.Header.Add("Authorization", token)
+		}
+
+		resp, err := client.Do(req)
+		r.NoError(err)
+
+		return resp
+	}
+
+	status := func(resp *http.Response) int {
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	signin := func(email string) (tokenResponse, int) {
+		resp := do(http.MethodPost, "/api/v1/signin", "", fmt.Sprintf(`{"email": "%s","password": "password"}`, email))
+		defer resp.Body.Close()
+
+		var tokens tokenResponse
+
+		if resp.StatusCode == http.StatusOK {
+			r.NoError(json.NewDecoder(resp.Body).Decode(&tokens))
+		}
+
+		return tokens, resp.StatusCode
+	}
+
+	for _, name := range []string{"admin", "trader"} {
+		u := fmt.Sprintf(`{"first_name": "%s","last_name": "Developer", "email": "%s@tinkoff.ru","password": "password"}`, name, name)
+		r.Equal(http.StatusCreated, status(do(http.MethodPost, "/api/v1/signup", "", u)))
+	}
+
+	trader, code := signin("trader@tinkoff.ru")
+	r.Equal(http.StatusOK, code)
+	r.Equal(http.StatusForbidden, status(do(http.MethodGet, "/api/v1/admin/users", trader.AccessToken, "")))
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodGet, "/api/v1/admin/users", "", "")))
+
+	adminUserData, err := h.userStorage.FindByEmail("admin@tinkoff.ru")
+	r.NoError(err)
+	r.NoError(h.userStorage.SetRole(adminUserData.ID, user.RoleAdmin))
+
+	admin, code := signin("admin@tinkoff.ru")
+	r.Equal(http.StatusOK, code)
+
+	resp := do(http.MethodGet, "/api/v1/admin/users?q=TRADER", admin.AccessToken, "")
+	r.Equal(http.StatusOK, resp.StatusCode)
+
+	var users []adminUser
+
+	r.NoError(json.NewDecoder(resp.Body).Decode(&users))
+	resp.Body.Close()
+	r.Len(users, 1)
+	r.Equal(trader.UserID, users[0].ID)
+	r.Equal(user.RoleUser, users[0].Role)
+
+	traderPath := fmt.Sprintf("/api/v1/admin/users/%d", trader.UserID)
+	r.Equal(http.StatusBadRequest, status(do(http.MethodPut, traderPath+"/role", admin.AccessToken, `{"role": "root"}`)))
+	r.Equal(http.StatusNotFound, status(do(http.MethodPut, "/api/v1/admin/users/100/lock", admin.AccessToken, "")))
+	r.Equal(http.StatusBadRequest, status(do(http.MethodPut, fmt.Sprintf("/api/v1/admin/users/%d/lock", admin.UserID), admin.AccessToken, "")))
+
+	// locking ends the sessions and keeps the user out
+	r.Equal(http.StatusOK, status(do(http.MethodGet, "/api/v1/robots", trader.AccessToken, "")))
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, traderPath+"/lock", admin.AccessToken, "")))
+	r.Equal(http.StatusForbidden, status(do(http.MethodGet, "/api/v1/robots", trader.AccessToken, "")))
+	_, code = signin("trader@tinkoff.ru")
+	r.Equal(http.StatusForbidden, code)
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodPost, "/api/v1/token/refresh", "",
+		fmt.Sprintf(`{"refresh_token": "%s"}`, trader.RefreshToken))))
+
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, traderPath+"/unlock", admin.AccessToken, "")))
+	trader, code = signin("trader@tinkoff.ru")
+	r.Equal(http.StatusOK, code)
+
+	r.Equal(http.StatusNoContent, status(do(http.MethodDelete, traderPath+"/sessions", admin.AccessToken, "")))
+	r.Equal(http.StatusUnauthorized, status(do(http.MethodPost, "/api/v1/token/refresh", "",
+		fmt.Sprintf(`{"refresh_token": "%s"}`, trader.RefreshToken))))
+
+	// role changes apply to the issued access tokens
+	trader, code = signin("trader@tinkoff.ru")
+	r.Equal(http.StatusOK, code)
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, traderPath+"/role", admin.AccessToken, `{"role": "admin"}`)))
+	r.Equal(http.StatusOK, status(do(http.MethodGet, "/api/v1/admin/users", trader.AccessToken, "")))
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, traderPath+"/role", admin.AccessToken, `{"role": "user"}`)))
+	r.Equal(http.StatusForbidden, status(do(http.MethodGet, "/api/v1/admin/users", trader.AccessToken, "")))
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, traderPath+"/role", admin.AccessToken, `{"role": "admin"}`)))
+
+	robotData := robot.Robot{OwnerUserID: trader.UserID, Ticker: "AAPL", BuyPrice: 1, SellPrice: 2}
+	r.NoError(h.robotStorage.Create(&robotData))
+
+	robotPath := fmt.Sprintf("/api/v1/admin/robots/%d", robotData.RobotID)
+	r.Equal(http.StatusBadRequest, status(do(http.MethodPut, robotPath+"/restore", admin.AccessToken, "")))
+	r.Equal(http.StatusNoContent, status(do(http.MethodDelete, robotPath, admin.AccessToken, "")))
+	r.Equal(http.StatusBadRequest, status(do(http.MethodDelete, robotPath, admin.AccessToken, "")))
+
+	// the owner can't activate the deleted robot
+	activate := fmt.Sprintf("/api/v1/robot/%d/activate", robotData.RobotID)
+	r.Equal(http.StatusNotFound, status(do(http.MethodPut, activate, trader.AccessToken, "")))
+
+	robots, err := h.robotStorage.GetAllRobotsByOwnerID(trader.UserID)
+	r.NoError(err)
+	r.Empty(robots)
+
+	r.Equal(http.StatusNoContent, status(do(http.MethodPut, robotPath+"/restore", admin.AccessToken, "")))
+
+	robots, err = h.robotStorage.GetAllRobotsByOwnerID(trader.UserID)
+	r.NoError(err)
+	r.Len(robots, 1)
+}
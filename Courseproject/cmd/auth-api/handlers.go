@@ -4,16 +4,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"../../internal/account"
+	"../../internal/backtest"
+	"../../internal/candles"
+	"../../internal/deal"
+	"../../internal/mail"
 	"../../internal/robot"
 	"../../internal/session"
+	"../../internal/strategy"
 	"../../internal/user"
 	"github.com/go-chi/chi"
 	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -22,10 +32,61 @@ type Handler struct {
 	userStorage    user.Storage
 	sessionStorage session.Storage
 	robotStorage   robot.Storage
+	dealStorage    deal.Storage
+	accountStorage account.Storage
 	upgrader       websocket.Upgrader
 	tmpl           map[string]*template.Template
 	wsClients      WSClients
 	robotsChan     chan robot.Robot
+	scheduler      Scheduler
+	signer         *session.Signer
+	refreshTTL     time.Duration
+	sliding        bool
+	mailer         mail.Mailer
+	publicURL      string
+	mails          sync.WaitGroup // mails sent in the background
+}
+
+// AuthConfig sets up access and refresh tokens, sliding sessions live RefreshTTL since
+// the last refresh, other ones since the signin
+type AuthConfig struct {
+	TokenKey   string
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+	Sliding    bool
+	Janitor    session.JanitorConfig
+	PublicURL  string // the base of links sent by email
+}
+
+// Scheduler starts and stops robots in the background engine
+type Scheduler interface {
+	Schedule(id int64)
+}
+
+// schedule tells the engine that the robot has changed
+func (h *Handler) schedule(id int64) {
+	if h.scheduler != nil {
+		h.scheduler.Schedule(id)
+	}
+}
+
+// release returns the capital of the robot which doesn't trade anymore to its owner,
+// the capital of an open position stays reserved until the engine closes it
+func (h *Handler) release(id int64) {
+	pos, err := h.robotStorage.FindPositionByID(id)
+	if err != nil {
+		h.logger.Errorf("Can't get position of robot %d: %s", id, err)
+		return
+	}
+
+	if pos.Side == robot.Bought {
+		h.logger.Infof("Capital of robot %d stays reserved for its open position", id)
+		return
+	}
+
+	if err := h.accountStorage.Release(id); err != nil {
+		h.logger.Errorf("Can't release capital of robot %d: %s", id, err)
+	}
 }
 
 type WSClients struct {
@@ -36,33 +97,80 @@ type WSClients struct {
 func (clients *WSClients) AddClient(h *Handler, conn *websocket.Conn) {
 	clients.mutex.Lock()
 	clients.wsConn = append(clients.wsConn, conn)
+	total := len(clients.wsConn)
 	clients.mutex.Unlock()
-	h.logger.Infof("added client, total clients: %d\n", len(h.wsClients.wsConn))
+	wsClientsConnected.Inc()
+	h.logger.Infof("added client, total clients: %d\n", total)
 }
 
-func (clients *WSClients) removeClientByID(h *Handler, id int) {
+func (clients *WSClients) removeClient(h *Handler, conn *websocket.Conn) {
 	clients.mutex.Lock()
-	clients.wsConn = append(clients.wsConn[:id], clients.wsConn[id+1:]...)
+
+	for i, c := range clients.wsConn {
+		if c == conn {
+			clients.wsConn = append(clients.wsConn[:i], clients.wsConn[i+1:]...)
+			wsClientsConnected.Dec()
+
+			break
+		}
+	}
+
+	total := len(clients.wsConn)
 	clients.mutex.Unlock()
-	h.logger.Infof("removed client #%d, total clients %d\n", id, len(clients.wsConn))
+	h.logger.Infof("removed client, total clients %d\n", total)
 }
 
 func (clients *WSClients) BroadcastMessage(h *Handler, message []byte) {
-	for i, c := range clients.wsConn {
+	clients.mutex.Lock()
+	conns := append([]*websocket.Conn(nil), clients.wsConn...)
+	clients.mutex.Unlock()
+
+	for _, c := range conns {
 		err := c.WriteMessage(websocket.TextMessage, message)
 		if err != nil {
 			h.logger.Infof("can't broadcast message: %+v\n", err)
-			clients.removeClientByID(h, i)
+			wsErrorsTotal.Inc()
+			clients.removeClient(h, c)
+			_ = c.Close()
+
+			continue
 		}
+
+		wsMessagesTotal.Inc()
+	}
+}
+
+// broadcastRobots delivers every robot update to all websocket clients,
+// updates are consumed even when nobody is connected
+func (h *Handler) broadcastRobots() {
+	for robot := range h.robotsChan {
+		res, err := json.Marshal(robot)
+		if err != nil {
+			h.logger.Infof("can't marshal message: %+v\n", err)
+			continue
+		}
+
+		h.wsClients.BroadcastMessage(h, res)
 	}
 }
 
 // nolint: gomnd
-func NewHandler(logger *zap.Logger, userStorage user.Storage, sessionStorage session.Storage, robotStorage robot.Storage) (*Handler, error) {
+func NewHandler(logger *zap.Logger, userStorage user.Storage, sessionStorage session.Storage, robotStorage robot.Storage,
+	dealStorage deal.Storage, accountStorage account.Storage, auth AuthConfig, mailer mail.Mailer) (*Handler, error) {
+	signer, err := session.NewSigner([]byte(auth.TokenKey), auth.AccessTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create token signer")
+	}
+
+	if auth.RefreshTTL <= 0 {
+		return nil, errors.Errorf("wrong refresh token ttl %v", auth.RefreshTTL)
+	}
+
 	templates := make(map[string]*template.Template)
 	templates["robots_list"] = template.Must(template.ParseFiles("html/robots.html", "html/base.html", "html/robot_table.html"))
 	templates["user_robots"] = template.Must(template.ParseFiles("html/user_robots.html", "html/base.html", "html/robot_table.html"))
 	templates["robot_info"] = template.Must(template.ParseFiles("html/robot_info.html", "html/base.html"))
+	templates["robot_deals"] = template.Must(template.ParseFiles("html/robot_deals.html", "html/base.html"))
 
 	var upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
@@ -74,40 +182,87 @@ func NewHandler(logger *zap.Logger, userStorage user.Storage, sessionStorage ses
 		userStorage:    userStorage,
 		sessionStorage: sessionStorage,
 		robotStorage:   robotStorage,
+		dealStorage:    dealStorage,
+		accountStorage: accountStorage,
 		upgrader:       upgrader,
 		tmpl:           templates,
+		signer:         signer,
+		refreshTTL:     auth.RefreshTTL,
+		sliding:        auth.Sliding,
+		mailer:         mailer,
+		publicURL:      strings.TrimSuffix(auth.PublicURL, "/"),
 	}
 	h.robotsChan = make(chan robot.Robot)
 
+	go h.broadcastRobots()
+
 	return &h, nil
 }
 
 func (h *Handler) NewRouter() http.Handler {
 	r := chi.NewRouter()
+	r.Use(instrument)
 
+	r.Handle("/metrics", promhttp.Handler())
+	// routes are public, authenticated by the session token or open to the owner only
 	r.Route("/api/v1", func(r chi.Router) {
 		r.Post("/signup", h.PostSignup)
 		r.Post("/signin", h.PostSignin)
+		r.Post("/token/refresh", h.RefreshToken)
+		r.Get("/email/verify", h.VerifyEmail)
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
+		r.With(h.authenticate).Post("/logout", h.Logout)
+		r.With(h.authenticate).Delete("/sessions/{id}", h.DeleteSession)
 		r.Route("/users/{id}", func(r chi.Router) {
-			r.Put("/", h.PutUser)
+			r.Use(h.authenticate)
 			r.Get("/", h.GetUser)
 			r.Get("/robots", h.GetUserRobots)
+			r.Group(func(r chi.Router) {
+				r.Use(h.userOwner)
+				r.Put("/", h.PutUser)
+				r.Get("/sessions", h.GetUserSessions)
+				r.Post("/verification", h.ResendVerification)
+				r.Post("/deposit", h.Deposit)
+				r.Post("/withdraw", h.Withdraw)
+			})
 		})
 		r.Route("/robot", func(r chi.Router) {
-			r.Post("/", h.CreateRobot)
+			r.With(h.authenticate).Post("/", h.CreateRobot)
 			r.Route("/{id}", func(r chi.Router) {
-				r.Delete("/", h.DeleteRobotByID)
+				r.Use(h.authenticate)
 				// r.Put("/", h.UpdateRobotByID)
 				r.Get("/", h.GetRobotDetails)
+				r.Get("/deals", h.GetRobotDeals)
+				r.Post("/backtest", h.BacktestRobot)
 				r.Put("/favorite", h.AddRobotToFavorite)
-				r.Put("/activate", h.ActivateRobot)
-				r.Put("/deactivate", h.DeactivateRobot)
+				r.Group(func(r chi.Router) {
+					r.Use(h.robotOwner)
+					r.Delete("/", h.DeleteRobotByID)
+					r.Put("/activate", h.ActivateRobot)
+					r.Put("/deactivate", h.DeactivateRobot)
+				})
 			})
 			r.HandleFunc("/robots_ws", h.WSRobotUpdate)
 		})
 		r.Route("/robots", func(r chi.Router) {
+			r.Use(h.authenticate)
 			r.Get("/", h.GetRobots)
 		})
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(h.authenticate, h.requireRole(user.RoleAdmin))
+			r.Get("/users", h.AdminGetUsers)
+			r.Route("/users/{id}", func(r chi.Router) {
+				r.Put("/lock", h.AdminLockUser)
+				r.Put("/unlock", h.AdminUnlockUser)
+				r.Put("/role", h.AdminSetRole)
+				r.Delete("/sessions", h.AdminDeleteSessions)
+			})
+			r.Route("/robots/{id}", func(r chi.Router) {
+				r.Delete("/", h.AdminDeleteRobot)
+				r.Put("/restore", h.AdminRestoreRobot)
+			})
+		})
 	})
 
 	return r
@@ -142,6 +297,9 @@ func (h *Handler) PostSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userData.Password = passwordHash
+	userData.EmailVerified = false
+	userData.Role = user.RoleUser
+	userData.Locked = false
 
 	if err := h.userStorage.Create(&userData); err != nil {
 		h.logger.Errorf("Can't add user: %s", err)
@@ -150,6 +308,8 @@ func (h *Handler) PostSignup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.sendVerification(userData)
+
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -170,26 +330,20 @@ func (h *Handler) PostSignin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sess, err := h.sessionStorage.FindByID(u.ID)
-	if err == nil {
-		if time.Now().Before(sess.ValidUntil) {
-			err = json.NewEncoder(w).Encode(sess)
-			if err != nil {
-				http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
-			}
-
-			return
-		}
-
-		if err = h.sessionStorage.DeleteByID(u.ID); err != nil {
-			h.logger.Errorf("%s", err)
-		}
+	if u.Locked {
+		userLocked(w)
+		return
 	}
 
 	token, _ := session.GenerateToken()
+	family, _ := session.GenerateToken()
 	sessionData := session.Session{
-		SessionID: token,
-		UserID:    u.ID,
+		SessionID:  token,
+		UserID:     u.ID,
+		Family:     family,
+		UserAgent:  r.UserAgent(),
+		IP:         clientIP(r),
+		ValidUntil: time.Now().Add(h.refreshTTL),
 	}
 
 	if err = h.sessionStorage.Create(&sessionData); err != nil {
@@ -199,27 +353,216 @@ func (h *Handler) PostSignin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = json.NewEncoder(w).Encode(sessionData)
+	h.sendTokens(w, &sessionData, u.Role)
+}
+
+// tokenResponse is returned by signin and refresh, the access token is sent in the Authorization
+// header and the refresh token gets the next pair of tokens once
+type tokenResponse struct {
+	UserID       int64  `json:"user_id"`
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshToken rotates the refresh token, a reused one revokes all sessions of its family
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req refreshRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "{\"error\": \"wrong input data\"}", http.StatusBadRequest)
+		return
+	}
+
+	sess, err := h.sessionStorage.FindByToken(req.RefreshToken)
+	if err != nil || !time.Now().Before(sess.ValidUntil) {
+		h.logger.Infof("Unvalid refresh token: %v", err)
+		unauthorized(w)
+
+		return
+	}
+
+	u, err := h.userStorage.FindByID(sess.UserID)
 	if err != nil {
+		h.logger.Errorf("Can't find user of session: %s", err)
+		unauthorized(w)
+
+		return
+	}
+
+	if u.Locked {
+		userLocked(w)
+		return
+	}
+
+	token, _ := session.GenerateToken()
+	next := session.Session{
+		SessionID:  token,
+		UserID:     sess.UserID,
+		Family:     sess.Family,
+		UserAgent:  r.UserAgent(),
+		IP:         clientIP(r),
+		ValidUntil: sess.ValidUntil,
+	}
+
+	if h.sliding {
+		next.ValidUntil = time.Now().Add(h.refreshTTL)
+	}
+
+	if !sess.Rotated {
+		err = h.sessionStorage.Rotate(sess.SessionID, &next)
+	}
+
+	if sess.Rotated || errors.Cause(err) == session.ErrTokenReused {
+		h.logger.Warnf("Refresh token of user %d is reused, revoking its sessions", sess.UserID)
+
+		if err = h.sessionStorage.DeleteFamily(sess.Family); err != nil {
+			h.logger.Errorf("Can't revoke sessions: %s", err)
+		}
+
+		unauthorized(w)
+
+		return
+	}
+
+	if err != nil {
+		h.logger.Errorf("Can't rotate session: %s", err)
+		http.Error(w, "{\"error\": \"can't refresh token\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	h.sendTokens(w, &next, u.Role)
+}
+
+// sendTokens signs the access token of the session user and returns it with the refresh token
+func (h *Handler) sendTokens(w http.ResponseWriter, sess *session.Session, role string) {
+	access, err := h.signer.Sign(sess.UserID, role, sess.Family, time.Now())
+	if err != nil {
+		h.logger.Errorf("Can't sign token: %s", err)
+		http.Error(w, "{\"error\": \"can't sign token\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	resp := tokenResponse{
+		UserID:       sess.UserID,
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(h.signer.TTL() / time.Second),
+		RefreshToken: sess.SessionID,
+	}
+
+	if err = json.NewEncoder(w).Encode(resp); err != nil {
 		http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
 		return
 	}
 }
 
-func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+// clientIP returns the address of the client without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// sessionInfo shows the session of a device to its user, the id is the family of refresh tokens
+type sessionInfo struct {
+	ID          string    `json:"id"`
+	UserAgent   string    `json:"user_agent"`
+	IP          string    `json:"ip"`
+	Current     bool      `json:"current"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	ValidUntil  time.Time `json:"valid_until"`
+}
+
+// Logout revokes the session of the access token, the token itself stays valid until it expires
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessionStorage.DeleteFamily(claimsFromContext(r.Context()).SessionID); err != nil {
+		h.logger.Errorf("Can't revoke session: %s", err)
+		http.Error(w, "{\"error\": \"can't revoke session\"}", http.StatusInternalServerError)
+
 		return
 	}
 
-	token := r.Header.Get("Authorization")
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) GetUserSessions(w http.ResponseWriter, r *http.Request) {
+	claims := claimsFromContext(r.Context())
+
+	sessions, err := h.sessionStorage.FindByUserID(claims.UserID)
+	if err != nil {
+		h.logger.Errorf("Can't get sessions: %s", err)
+		http.Error(w, "{\"error\": \"can't get sessions\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	result := make([]sessionInfo, 0, len(sessions))
 
-	sess, err := h.sessionStorage.FindByID(id)
-	if err != nil || token != sess.SessionID || !time.Now().Before(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusNotAcceptable)
+	for _, sess := range sessions {
+		result = append(result, sessionInfo{
+			ID:          sess.Family,
+			UserAgent:   sess.UserAgent,
+			IP:          sess.IP,
+			Current:     sess.Family == claims.SessionID,
+			RefreshedAt: sess.CreatedAt,
+			ValidUntil:  sess.ValidUntil,
+		})
+	}
 
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
+		return
+	}
+}
+
+// DeleteSession revokes the session of the user on another device
+func (h *Handler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	sessions, err := h.sessionStorage.FindByUserID(claimsFromContext(r.Context()).UserID)
+	if err != nil {
+		h.logger.Errorf("Can't get sessions: %s", err)
+		http.Error(w, "{\"error\": \"can't get sessions\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	found := false
+
+	for _, sess := range sessions {
+		found = found || sess.Family == id
+	}
+
+	if !found {
+		http.Error(w, "{\"error\": \"session not found\"}", http.StatusNotFound)
+		return
+	}
+
+	if err = h.sessionStorage.DeleteFamily(id); err != nil {
+		h.logger.Errorf("Can't revoke session: %s", err)
+		http.Error(w, "{\"error\": \"can't revoke session\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
 		return
 	}
 
@@ -230,13 +573,27 @@ func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	old, err := h.userStorage.FindByID(id)
+	if err != nil {
+		http.Error(w, "{\"error\": \"could not find\"}", http.StatusNotFound)
+		return
+	}
+
 	userData.ID = id
+	// a new email has to be verified again
+	userData.EmailVerified = old.EmailVerified && userData.Email == old.Email
+	userData.Role = old.Role
+	userData.Locked = old.Locked
 
 	if err = h.userStorage.UpdateByID(&userData); err != nil {
 		http.Error(w, "{\"error\": \"could not update\"}", http.StatusInternalServerError)
 		return
 	}
 
+	if userData.Email != old.Email {
+		h.sendVerification(userData)
+	}
+
 	u, err := h.userStorage.FindByID(id)
 	if err != nil {
 		http.Error(w, "{\"error\": \"could not find\"}", http.StatusNotFound)
@@ -253,16 +610,6 @@ func (h *Handler) PutUser(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		http.Error(w, "{\"error\": \"bad data\"}", http.StatusInternalServerError)
@@ -276,6 +623,18 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	userShort := user.ShortUser{FirstName: u.FirstName, LastName: u.LastName, Email: u.Email, Birthday: u.Birthday}
+
+	if claimsFromContext(r.Context()).UserID == id {
+		userShort.EmailVerified = u.EmailVerified
+
+		if userShort.Account, err = h.accountStorage.FindByUserID(id); err != nil {
+			h.logger.Errorf("Can't get account: %s", err)
+			http.Error(w, "{\"error\": \"can't get account\"}", http.StatusInternalServerError)
+
+			return
+		}
+	}
+
 	err = json.NewEncoder(w).Encode(userShort)
 
 	if err != nil {
@@ -284,25 +643,89 @@ func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (h *Handler) CreateRobot(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
+type amountRequest struct {
+	Amount float64 `json:"amount"`
+}
 
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
+func (h *Handler) Deposit(w http.ResponseWriter, r *http.Request) {
+	h.changeBalance(w, r, h.accountStorage.Deposit)
+}
+
+func (h *Handler) Withdraw(w http.ResponseWriter, r *http.Request) {
+	h.changeBalance(w, r, h.accountStorage.Withdraw)
+}
 
+// changeBalance applies the deposit or the withdrawal to the account of the user and returns the account
+func (h *Handler) changeBalance(w http.ResponseWriter, r *http.Request, change func(userID int64, amount float64) error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	var req amountRequest
+
+	if err = json.NewDecoder(r.Body).Decode(&req); err != nil || account.CheckAmount(req.Amount) != nil {
+		http.Error(w, "{\"error\": \"wrong input data\"}", http.StatusBadRequest)
 		return
 	}
 
+	if err = change(id, req.Amount); err != nil {
+		h.logger.Errorf("Can't change balance: %s", err)
+
+		if errors.Cause(err) == account.ErrInsufficientFunds {
+			http.Error(w, "{\"error\": \"insufficient funds\"}", http.StatusBadRequest)
+		} else {
+			http.Error(w, "{\"error\": \"could not update\"}", http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	a, err := h.accountStorage.FindByUserID(id)
+	if err != nil {
+		http.Error(w, "{\"error\": \"could not find\"}", http.StatusInternalServerError)
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(a); err != nil {
+		http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
+		return
+	}
+}
+
+func (h *Handler) CreateRobot(w http.ResponseWriter, r *http.Request) {
 	var robotData robot.Robot
 
-	err = json.NewDecoder(r.Body).Decode(&robotData)
-	if err != nil || robotData.BuyPrice >= robotData.SellPrice{
+	err := json.NewDecoder(r.Body).Decode(&robotData)
+	if err != nil || robotData.BuyPrice >= robotData.SellPrice {
 		http.Error(w, "{\"error\": \"wrong input data\"}", http.StatusBadRequest)
 		return
 	}
 
+	robotData.OwnerUserID = claimsFromContext(r.Context()).UserID
+
+	if _, err = strategy.New(&robotData); err != nil {
+		h.logger.Errorf("Wrong strategy: %s", err)
+		http.Error(w, "{\"error\": \"wrong strategy\"}", http.StatusBadRequest)
+
+		return
+	}
+
+	if err = robotData.ValidateSizing(); err != nil {
+		h.logger.Errorf("Wrong sizing: %s", err)
+		http.Error(w, "{\"error\": \"wrong lot size, max position or commission\"}", http.StatusBadRequest)
+
+		return
+	}
+
+	if err = robotData.ValidateRisk(); err != nil {
+		h.logger.Errorf("Wrong risk limits: %s", err)
+		http.Error(w, "{\"error\": \"wrong stop-loss, take-profit or max drawdown\"}", http.StatusBadRequest)
+
+		return
+	}
+
 	if err := h.robotStorage.Create(&robotData); err != nil {
 		h.logger.Errorf("Can't add robot: %s", err)
 		http.Error(w, "{\"error\": \"can't create robot\"}", http.StatusBadRequest)
@@ -310,20 +733,12 @@ func (h *Handler) CreateRobot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.schedule(robotData.RobotID)
+
 	w.WriteHeader(http.StatusCreated)
 }
 
 func (h *Handler) GetUserRobots(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusNotAcceptable)
-
-		return
-	}
-
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		http.Error(w, "{\"error\": \"bad data\"}", http.StatusInternalServerError)
@@ -394,22 +809,14 @@ func (h *Handler) getListOfRobots(id int64, ticker string) ([]*robot.Robot, int,
 
 // nolint: gomnd
 func (h *Handler) GetRobots(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	var robots []*robot.Robot
 
 	var id int64
 
 	var ticker string
 
+	var err error
+
 	query := r.URL.Query()
 	ownerID, idOk := query["owner_user_id"]
 	tickerList, tickerOk := query["ticker"]
@@ -464,16 +871,6 @@ func (h *Handler) DeleteRobotByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	robotData, err := h.robotStorage.FindByID(id)
 	if err != nil {
 		h.logger.Errorf("Can't get robot: %s", err)
@@ -484,7 +881,7 @@ func (h *Handler) DeleteRobotByID(w http.ResponseWriter, r *http.Request) {
 
 	// h.logger.Info(robotData.DeletedAt)
 
-	if sess.UserID != robotData.OwnerUserID || robotData.DeletedAt.Valid {
+	if robotData.DeletedAt.Valid {
 		h.logger.Infof("No properties to delete")
 		http.Error(w, "{\"error\": \"not available\"}", http.StatusBadRequest)
 
@@ -496,19 +893,13 @@ func (h *Handler) DeleteRobotByID(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "{\"error\": \"error while deleting robot\"}", http.StatusInternalServerError)
 		return
 	}
-}
-
-func (h *Handler) AddRobotToFavorite(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
 
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
+	h.release(id)
 
-		return
-	}
+	h.schedule(id)
+}
 
+func (h *Handler) AddRobotToFavorite(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		http.Error(w, "{\"error\": \"bad data\"}", http.StatusInternalServerError)
@@ -523,10 +914,15 @@ func (h *Handler) AddRobotToFavorite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	robotData.OwnerUserID = sess.UserID
+	robotData.OwnerUserID = claimsFromContext(r.Context()).UserID
 	robotData.ParentRobotID = id
 	robotData.FactYield = 0
+	robotData.RealizedPnL = 0
+	robotData.UnrealizedPnL = 0
 	robotData.DealsCount = 0
+	robotData.Fees = 0
+	robotData.PeakYield = 0
+	robotData.StopReason = ""
 	robotData.IsFavorite = true
 	robotData.CreatedAt = time.Now()
 
@@ -537,6 +933,7 @@ func (h *Handler) AddRobotToFavorite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.schedule(robotData.RobotID)
 	h.robotsChan <- *robotData
 	err = json.NewEncoder(w).Encode(robotData)
 
@@ -546,16 +943,6 @@ func (h *Handler) AddRobotToFavorite(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) ActivateRobot(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || !time.Now().Before(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
@@ -572,20 +959,48 @@ func (h *Handler) ActivateRobot(w http.ResponseWriter, r *http.Request) {
 
 
 
-	if robotData.IsActive || robotData.OwnerUserID != sess.UserID || time.Now().After(robotData.PlanStart) && time.Now().Before(robotData.PlanEnd) {
+	owner, err := h.userStorage.FindByID(robotData.OwnerUserID)
+	if err != nil {
+		h.logger.Errorf("Can't get owner of robot: %s", err)
+		http.Error(w, "{\"error\": \"can't get owner of robot\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	if !owner.EmailVerified {
+		http.Error(w, "{\"error\": \"email is not verified\"}", http.StatusForbidden)
+		return
+	}
+
+	if robotData.IsActive || time.Now().After(robotData.PlanStart) && time.Now().Before(robotData.PlanEnd) {
 		h.logger.Errorf("Can't activate robot")
 		http.Error(w, "{\"error\": \"can't activate robot now\"}", http.StatusBadRequest)
 
 		return
 	}
 
+	if err := h.accountStorage.Reserve(robotData.OwnerUserID, id, robotData.Capital()); err != nil {
+		h.logger.Errorf("Can't reserve capital of robot: %s", err)
+
+		if errors.Cause(err) == account.ErrInsufficientFunds {
+			http.Error(w, "{\"error\": \"insufficient funds\"}", http.StatusBadRequest)
+		} else {
+			http.Error(w, "{\"error\": \"error while reserving capital\"}", http.StatusInternalServerError)
+		}
+
+		return
+	}
+
 	if err := h.robotStorage.ActivateByID(id); err != nil {
 		h.logger.Errorf("Can't activate robot: %s", err)
+		h.release(id)
 		http.Error(w, "{\"error\": \"error while activating robot\"}", http.StatusInternalServerError)
 
 		return
 	}
 
+	h.schedule(id)
+
 	robotData, err = h.robotStorage.FindByID(id)
 	if err != nil {
 		h.logger.Errorf("Can't get parent robot: %s", err)
@@ -598,16 +1013,6 @@ func (h *Handler) ActivateRobot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) DeactivateRobot(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
@@ -622,9 +1027,11 @@ func (h *Handler) DeactivateRobot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if !robotData.IsActive || robotData.OwnerUserID != sess.UserID || time.Now().After(robotData.PlanStart) && time.Now().Before(robotData.PlanEnd) {
+	if !robotData.IsActive || time.Now().After(robotData.PlanStart) && time.Now().Before(robotData.PlanEnd) {
 		h.logger.Errorf("Can't activate robot")
 		http.Error(w, "{\"error\": \"can't activate robot now\"}", http.StatusBadRequest)
+
+		return
 	}
 
 	if err = h.robotStorage.DeactivateByID(id); err != nil {
@@ -634,6 +1041,10 @@ func (h *Handler) DeactivateRobot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.release(id)
+
+	h.schedule(id)
+
 	robotData, err = h.robotStorage.FindByID(id)
 	if err != nil {
 		h.logger.Errorf("Can't get robot: %s", err)
@@ -645,16 +1056,6 @@ func (h *Handler) DeactivateRobot(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) GetRobotDetails(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-
-	sess, err := h.sessionStorage.FindByToken(token)
-	if err != nil || time.Now().After(sess.ValidUntil) {
-		h.logger.Errorf("Unvalid token: %s", err)
-		http.Error(w, "{\"error\": \"unvalid token\"}", http.StatusBadRequest)
-
-		return
-	}
-
 	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
 		h.logger.Errorf("Can't parse robot id: %s", err)
@@ -685,6 +1086,146 @@ func (h *Handler) GetRobotDetails(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Paging of the deal history
+const (
+	defaultDealsLimit = 20
+	maxDealsLimit     = 100
+)
+
+// parsePaging reads limit and offset query parameters
+func parsePaging(r *http.Request) (limit, offset int, err error) {
+	limit = defaultDealsLimit
+	query := r.URL.Query()
+
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			return 0, 0, fmt.Errorf("bad limit %q", v)
+		}
+	}
+
+	if limit > maxDealsLimit {
+		limit = maxDealsLimit
+	}
+
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("bad offset %q", v)
+		}
+	}
+
+	return limit, offset, nil
+}
+
+func (h *Handler) GetRobotDeals(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	limit, offset, err := parsePaging(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("{\"error\": \"%s\"}", err), http.StatusBadRequest)
+		return
+	}
+
+	if _, err = h.robotStorage.FindByID(id); err != nil {
+		h.logger.Errorf("Can't get robot: %s", err)
+		http.Error(w, "{\"error\": \"can't get robot\"}", http.StatusNotFound)
+
+		return
+	}
+
+	deals, err := h.dealStorage.GetByRobotID(id, limit, offset)
+	if err != nil {
+		h.logger.Errorf("Can't get deals: %s", err)
+		http.Error(w, "{\"error\": \"can't get deals\"}", http.StatusInternalServerError)
+
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+
+	switch accept {
+	case "application/json":
+		if deals == nil {
+			deals = []*deal.Deal{}
+		}
+
+		err = json.NewEncoder(w).Encode(deals)
+		if err != nil {
+			http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
+			return
+		}
+	default:
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+
+		h.renderTemplate(w, "robot_deals", "base", struct {
+			RobotID    int64
+			Deals      []*deal.Deal
+			Limit      int
+			Offset     int
+			PrevOffset int
+			NextOffset int
+			HasNext    bool
+		}{id, deals, limit, offset, prev, offset + limit, len(deals) == limit})
+	}
+}
+
+// maxBacktestSize limits the candles uploaded for a backtest
+const maxBacktestSize = 32 << 20
+
+// BacktestRobot replays the candles csv from the request body through the robot strategy,
+// the spread query parameter sets the relative difference between buy and sell prices
+func (h *Handler) BacktestRobot(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "{\"error\": \"bad data\"}", http.StatusBadRequest)
+		return
+	}
+
+	var spread float64
+
+	if v := r.URL.Query().Get("spread"); v != "" {
+		if spread, err = strconv.ParseFloat(v, 64); err != nil || spread < 0 {
+			http.Error(w, "{\"error\": \"bad spread\"}", http.StatusBadRequest)
+			return
+		}
+	}
+
+	robotData, err := h.robotStorage.FindByID(id)
+	if err != nil {
+		h.logger.Errorf("Can't get robot: %s", err)
+		http.Error(w, "{\"error\": \"can't get robot\"}", http.StatusNotFound)
+
+		return
+	}
+
+	series, err := candles.ReadCSV(http.MaxBytesReader(w, r.Body, maxBacktestSize))
+	if err != nil {
+		h.logger.Errorf("Can't read candles: %s", err)
+		http.Error(w, "{\"error\": \"wrong candles\"}", http.StatusBadRequest)
+
+		return
+	}
+
+	result, err := backtest.Run(*robotData, series, spread)
+	if err != nil {
+		h.logger.Errorf("Can't backtest robot: %s", err)
+		http.Error(w, fmt.Sprintf("{\"error\": \"can't backtest robot %d\"}", id), http.StatusBadRequest)
+
+		return
+	}
+
+	if err = json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, "{\"error\": \"can't return data\"}", http.StatusInternalServerError)
+		return
+	}
+}
+
 func (h *Handler) WSRobotUpdate(w http.ResponseWriter, r *http.Request) {
 	h.logger.Infof("New ws client\n")
 
@@ -693,20 +1234,17 @@ func (h *Handler) WSRobotUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.wsClients.AddClient(h, conn)
+
 	defer func() {
+		h.wsClients.removeClient(h, conn)
 		_ = conn.Close()
 	}()
-	h.wsClients.AddClient(h, conn)
 
+	// updates are written by broadcastRobots, reading only detects the closed connection
 	for {
-		robot := <-h.robotsChan
-		res, err := json.Marshal(robot)
-
-		if err != nil {
-			h.logger.Infof("can't marshal message: %+v\n", err)
-			continue
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
 		}
-
-		h.wsClients.BroadcastMessage(h, res)
 	}
 }
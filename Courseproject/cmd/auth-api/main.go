@@ -14,15 +14,22 @@ import (
 	"syscall"
 
 	"../../internal/background"
+	"../../internal/calendar"
+	"../../internal/mail"
 	"../../internal/postgres"
+	"../../internal/session"
 	"go.uber.org/zap"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 type Config struct {
-	ListenAddr  string
-	DB          postgres.Config
-	Base64DBURL string
+	ListenAddr   string
+	DB           postgres.Config
+	Base64DBURL  string
+	CalendarFile string
+	Auth         AuthConfig
+	Mail         mail.Config
+	Background   background.Config
 }
 
 func parseFlags() Config {
@@ -45,6 +52,65 @@ func parseFlags() Config {
 		Envar("BASE64_DB_URL").Default("").
 		StringVar(&cfg.Base64DBURL)
 
+	kingpin.Flag("token-key", "Key signing access tokens, a random one invalidates tokens on restart.").
+		Envar("TOKEN_KEY").Default("").
+		StringVar(&cfg.Auth.TokenKey)
+	kingpin.Flag("access-token-ttl", "Lifetime of access tokens.").
+		Envar("ACCESS_TOKEN_TTL").Default("15m").
+		DurationVar(&cfg.Auth.AccessTTL)
+	kingpin.Flag("refresh-token-ttl", "Lifetime of sessions and their refresh tokens.").
+		Envar("REFRESH_TOKEN_TTL").Default("720h").
+		DurationVar(&cfg.Auth.RefreshTTL)
+	kingpin.Flag("session-sliding", "Renew the lifetime of a session on every token refresh.").
+		Envar("SESSION_SLIDING").Default("true").
+		BoolVar(&cfg.Auth.Sliding)
+	kingpin.Flag("session-cleanup-interval", "Interval of purging expired sessions.").
+		Envar("SESSION_CLEANUP_INTERVAL").Default("10m").
+		DurationVar(&cfg.Auth.Janitor.Interval)
+	kingpin.Flag("session-cleanup-batch", "Expired sessions deleted by one query.").
+		Envar("SESSION_CLEANUP_BATCH").Default("1000").
+		IntVar(&cfg.Auth.Janitor.BatchSize)
+
+	kingpin.Flag("public-url", "Base URL of the API in links sent by email.").
+		Envar("PUBLIC_URL").Default("http://localhost:8000").
+		StringVar(&cfg.Auth.PublicURL)
+	kingpin.Flag("smtp-addr", "SMTP server host:port, emails are kept in the outbox without it.").
+		Envar("SMTP_ADDR").Default("").
+		StringVar(&cfg.Mail.SMTPAddr)
+	kingpin.Flag("smtp-username", "SMTP username.").
+		Envar("SMTP_USERNAME").Default("").
+		StringVar(&cfg.Mail.Username)
+	kingpin.Flag("smtp-password", "SMTP password.").
+		Envar("SMTP_PASSWORD").Default("").
+		StringVar(&cfg.Mail.Password)
+	kingpin.Flag("mail-from", "Sender of emails.").
+		Envar("MAIL_FROM").Default("robots@localhost").
+		StringVar(&cfg.Mail.From)
+	kingpin.Flag("mail-outbox", "Directory of the outbox keeping emails without SMTP.").
+		Envar("MAIL_OUTBOX").Default("outbox").
+		StringVar(&cfg.Mail.OutboxDir)
+
+	cfg.Background.Reconnect = background.DefaultReconnectPolicy
+
+	kingpin.Flag("streamer-addr", "Price streamer address.").
+		Envar("STREAMER_ADDR").Default("localhost:5000").
+		StringVar(&cfg.Background.StreamerAddr)
+	kingpin.Flag("exchange-addr", "Order service address, the price streamer one by default.").
+		Envar("EXCHANGE_ADDR").Default("").
+		StringVar(&cfg.Background.ExchangeAddr)
+	kingpin.Flag("streamer-reconnect-delay", "Initial delay before reconnecting to the price streamer.").
+		Envar("STREAMER_RECONNECT_DELAY").Default(cfg.Background.Reconnect.InitialDelay.String()).
+		DurationVar(&cfg.Background.Reconnect.InitialDelay)
+	kingpin.Flag("streamer-reconnect-max-delay", "Maximum delay before reconnecting to the price streamer.").
+		Envar("STREAMER_RECONNECT_MAX_DELAY").Default(cfg.Background.Reconnect.MaxDelay.String()).
+		DurationVar(&cfg.Background.Reconnect.MaxDelay)
+	kingpin.Flag("mark-interval", "How often the PnL robots are marked at by prices is stored.").
+		Envar("MARK_INTERVAL").Default(background.DefaultMarkInterval.String()).
+		DurationVar(&cfg.Background.MarkInterval)
+	kingpin.Flag("calendar", "Trading calendar of exchanges, robots trade around the clock without it.").
+		Envar("CALENDAR").Default("").
+		StringVar(&cfg.CalendarFile)
+
 	kingpin.Parse()
 
 	if cfg.Base64DBURL != "" {
@@ -97,7 +163,41 @@ func main() {
 
 	defer handleCloser(logger, "robot_storage", robotStorage)
 
-	h, err := NewHandler(logger, userStorage, sessionStorage, robotStorage)
+	orderStorage, err := postgres.NewOrderStorage(db)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create order storage: %s", err)
+	}
+
+	defer handleCloser(logger, "order_storage", orderStorage)
+
+	dealStorage, err := postgres.NewDealStorage(db)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create deal storage: %s", err)
+	}
+
+	defer handleCloser(logger, "deal_storage", dealStorage)
+
+	accountStorage, err := postgres.NewAccountStorage(db)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create account storage: %s", err)
+	}
+
+	defer handleCloser(logger, "account_storage", accountStorage)
+
+	if cfg.Auth.TokenKey == "" {
+		logger.Sugar().Warnf("Token key is not set, access tokens are signed by a random key")
+
+		if cfg.Auth.TokenKey, err = session.GenerateToken(); err != nil {
+			logger.Sugar().Fatalf("Can't generate token key: %s", err)
+		}
+	}
+
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create mailer: %s", err)
+	}
+
+	h, err := NewHandler(logger, userStorage, sessionStorage, robotStorage, dealStorage, accountStorage, cfg.Auth, mailer)
 	if err != nil {
 		logger.Sugar().Fatalf("Can't create server: %s", err)
 	}
@@ -116,7 +216,34 @@ func main() {
 
 	stopAppCh := make(chan struct{})
 
-	go background.NewBackground(h.logger, h.robotStorage, h.robotsChan)
+	if cfg.CalendarFile != "" {
+		cfg.Background.Calendar, err = calendar.LoadFile(cfg.CalendarFile)
+		if err != nil {
+			logger.Sugar().Fatalf("Can't load calendar: %s", err)
+		}
+	}
+
+	bg, err := background.NewBackground(h.logger, h.robotStorage, orderStorage, accountStorage, h.robotsChan, cfg.Background)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create background: %s", err)
+	}
+
+	h.scheduler = bg
+
+	go bg.Run(context.Background()) // nolint:errcheck // the error is reported by Stop
+
+	janitor, err := session.NewJanitor(h.logger, sessionStorage, cfg.Auth.Janitor)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create session janitor: %s", err)
+	}
+
+	janitorCtx, stopJanitor := context.WithCancel(context.Background())
+	janitorDone := make(chan struct{})
+
+	go func() {
+		defer close(janitorDone)
+		janitor.Run(janitorCtx)
+	}()
 
 	go func() {
 		s := <-sigquit
@@ -128,6 +255,17 @@ func main() {
 		}
 
 		fmt.Println("server stopped")
+
+		h.WaitMails()
+
+		if err := bg.Stop(); err != nil {
+			logger.Sugar().Errorf("Can't stop background: %s", err)
+		}
+
+		fmt.Println("background stopped")
+
+		stopJanitor()
+		<-janitorDone
 		stopAppCh <- struct{}{}
 	}()
 
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "fintech"
+
+var (
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "api",
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by their route and response code.",
+	}, []string{"method", "route", "code"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "api",
+		Name:      "http_request_duration_seconds",
+		Help:      "Time of handling HTTP requests by their route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+	wsClientsConnected = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ws",
+		Name:      "clients",
+		Help:      "Websocket clients receiving robot updates.",
+	})
+	wsMessagesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ws",
+		Name:      "messages_total",
+		Help:      "Robot updates sent to websocket clients.",
+	})
+	wsErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "ws",
+		Name:      "send_errors_total",
+		Help:      "Robot updates websocket clients failed to receive.",
+	})
+)
+
+// instrument counts requests and measures their time by chi route patterns,
+// requests which match no route are labeled "unknown"
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = "unknown"
+		}
+
+		code := ww.Status()
+		if code == 0 {
+			code = http.StatusOK
+		}
+
+		httpRequestsTotal.WithLabelValues(r.Method, route, strconv.Itoa(code)).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, route).Observe(time.Since(start).Seconds())
+	})
+}
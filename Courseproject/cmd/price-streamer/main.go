@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"../../internal/calendar"
+	"../../internal/exchange"
+	"../../internal/pricestream"
+	streamer "../../internal/streamer"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+type Config struct {
+	ListenAddr string
+	Source     string
+	File       string
+	DropAfter  int
+	Calendar   string
+	Stream     pricestream.Config
+	Exchange   exchange.Config
+}
+
+func parseFlags() Config {
+	var cfg Config
+
+	kingpin.Flag("listen-addr", "Listen address.").
+		Envar("LISTEN_ADDR").Default("5000").
+		StringVar(&cfg.ListenAddr)
+	kingpin.Flag("source", "Source of prices: csv or random.").
+		Envar("SOURCE").Default("csv").
+		EnumVar(&cfg.Source, "csv", "random")
+	kingpin.Flag("file", "Candles file to replay.").
+		Envar("FILE").Default("../../../Lesson3/HW/candles_5min.csv").
+		StringVar(&cfg.File)
+	kingpin.Flag("speed", "Playback speed, 1 means real time.").
+		Envar("SPEED").Default("60").
+		Float64Var(&cfg.Stream.Speed)
+	kingpin.Flag("spread", "Relative spread between buy and sell prices.").
+		Envar("SPREAD").Default("0.001").
+		Float64Var(&cfg.Stream.Spread)
+	kingpin.Flag("loop", "Replay candles again when they are over.").
+		Envar("LOOP").Default("true").
+		BoolVar(&cfg.Stream.Loop)
+	kingpin.Flag("seed", "Seed of the random walk.").
+		Envar("SEED").Default("1").
+		Int64Var(&cfg.Stream.Seed)
+	kingpin.Flag("start-price", "First price of the random walk.").
+		Envar("START_PRICE").Default("100").
+		Float64Var(&cfg.Stream.StartPrice)
+	kingpin.Flag("volatility", "Relative standard deviation of a random walk step.").
+		Envar("VOLATILITY").Default("0.002").
+		Float64Var(&cfg.Stream.Volatility)
+	kingpin.Flag("interval", "Time between random walk steps.").
+		Envar("INTERVAL").Default("1m").
+		DurationVar(&cfg.Stream.Interval)
+	kingpin.Flag("depth", "Order book levels on each side, 0 streams no book.").
+		Envar("DEPTH").Default("5").
+		IntVar(&cfg.Stream.Depth)
+	kingpin.Flag("level-size", "Size of the best order book levels, deeper levels are larger.").
+		Envar("LEVEL_SIZE").Default("10").
+		Int64Var(&cfg.Stream.LevelSize)
+	kingpin.Flag("drop-after", "Break every stream after this number of quotes, 0 keeps streams open.").
+		Envar("DROP_AFTER").Default("0").
+		IntVar(&cfg.DropAfter)
+	kingpin.Flag("calendar", "Trading calendar of exchanges for candles, candles are built on the 07:00-24:00 UTC session without it.").
+		Envar("CALENDAR").Default("").
+		StringVar(&cfg.Calendar)
+	kingpin.Flag("fill-interval", "Time between fills of an order.").
+		Envar("FILL_INTERVAL").Default("1s").
+		DurationVar(&cfg.Exchange.FillInterval)
+	kingpin.Flag("max-fill", "Maximum quantity filled at once, 0 fills orders completely.").
+		Envar("MAX_FILL").Default("0").
+		Int64Var(&cfg.Exchange.MaxFill)
+	kingpin.Flag("reject-rate", "Share of orders rejected at random.").
+		Envar("REJECT_RATE").Default("0").
+		Float64Var(&cfg.Exchange.RejectRate)
+
+	kingpin.Parse()
+
+	cfg.Exchange.Seed = cfg.Stream.Seed
+
+	return cfg
+}
+
+func newSource(cfg Config) (pricestream.Source, error) {
+	if cfg.Source == "random" {
+		return pricestream.NewRandomSource(cfg.Stream)
+	}
+
+	f, err := os.Open(cfg.File)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return pricestream.NewCSVSource(f, cfg.Stream)
+}
+
+func main() {
+	cfg := parseFlags()
+	logger, err := zap.NewDevelopment()
+
+	if err != nil {
+		log.Fatal("Can't create zap logger: ", err)
+	}
+
+	defer logger.Sync() // nolint:errcheck
+
+	source, err := newSource(cfg)
+	if err != nil {
+		logger.Sugar().Fatalf("Can't create %s source: %s", cfg.Source, err)
+	}
+
+	cal := calendar.New(calendar.DaySession)
+	if cfg.Calendar != "" {
+		cal, err = calendar.LoadFile(cfg.Calendar)
+		if err != nil {
+			logger.Sugar().Fatalf("Can't load calendar: %s", err)
+		}
+	}
+
+	lis, err := net.Listen("tcp", net.JoinHostPort("", cfg.ListenAddr))
+	if err != nil {
+		logger.Sugar().Fatalf("Can't listen: %s", err)
+	}
+
+	srv := grpc.NewServer()
+	streamer.RegisterTradingServiceServer(srv, pricestream.NewServer(logger.Sugar(), source, cal, cfg.DropAfter))
+
+	orders := exchange.New(logger.Sugar(), cfg.Exchange)
+	streamer.RegisterOrderServiceServer(srv, orders)
+
+	sigquit := make(chan os.Signal, 1)
+	signal.Notify(sigquit, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		s := <-sigquit
+		fmt.Printf("captured signal: %v\n", s)
+
+		_ = orders.Close()
+
+		stopped := make(chan struct{})
+
+		go func() {
+			srv.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second): // nolint: gomnd
+			srv.Stop()
+		}
+	}()
+
+	logger.Sugar().Infof("Price streamer and exchange started on %s with %s source", cfg.ListenAddr, cfg.Source)
+
+	if err := srv.Serve(lis); err != nil {
+		logger.Sugar().Fatalf("Can't serve: %s", err)
+	}
+}
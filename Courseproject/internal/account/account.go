@@ -0,0 +1,52 @@
+package account
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Account is the cash of the user, the balance is free to withdraw or to
+// reserve for robots, the reserved cash is spent and earned by robot deals
+type Account struct {
+	UserID    int64     `json:"user_id"`
+	Balance   float64   `json:"balance"`
+	Reserved  float64   `json:"reserved"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrWrongAmount       = errors.New("amount must be positive in whole kopecks")
+)
+
+type Storage interface {
+	// FindByUserID returns an empty account for users who have never deposited
+	FindByUserID(userID int64) (*Account, error)
+	Deposit(userID int64, amount float64) error
+	Withdraw(userID int64, amount float64) error
+	// Reserve moves the amount from the balance to the reservation of the robot
+	Reserve(userID, robotID int64, amount float64) error
+	// Release returns the rest of the robot reservation to the balance
+	Release(robotID int64) error
+	// Settle adds the cash of a robot deal to its reservation, buys beyond the
+	// reservation are paid from the balance and robots without a reservation
+	// settle with the balance directly
+	Settle(userID, robotID int64, cash float64) error
+}
+
+// CheckAmount validates the amount of a deposit or a withdrawal
+func CheckAmount(amount float64) error {
+	if amount <= 0 || Round(amount) != amount {
+		return errors.Wrapf(ErrWrongAmount, "amount %v", amount)
+	}
+
+	return nil
+}
+
+// Round rounds the amount to kopecks, storages keep money rounded so float
+// errors of robot deals don't pile up in balances
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -5,156 +5,386 @@ import (
 	"sync"
 	"time"
 
+	"../account"
+	"../calendar"
+	"../order"
 	"../robot"
+	"../strategy"
 	streamer "../streamer"
+	"github.com/pkg/errors"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
 )
 
-const (
-	Sold = iota
-	Bought
-)
+const minConnectTimeout = 20 * time.Second
+
+// ErrStopped is returned by Run of the engine that has already run or has been stopped
+var ErrStopped = errors.New("engine is already run or stopped")
+
+// DefaultMarkInterval is how often marked PnL of robots is stored by default
+const DefaultMarkInterval = time.Second
+
+type Config struct {
+	StreamerAddr string
+	// ExchangeAddr is the address of the order service, empty means the streamer one
+	ExchangeAddr string
+	Reconnect    ReconnectPolicy
+	// Calendar limits robots to trading hours of their exchanges, nil trades around the clock
+	Calendar *calendar.Calendar
+	// MarkInterval is how often the PnL robots are marked at by ticks is stored, ticks
+	// don't wait for the storage. Zero means DefaultMarkInterval
+	MarkInterval time.Duration
+}
 
 type Background struct {
-	logger        *zap.SugaredLogger
-	robotStorage  robot.Storage
-	robotsChan    chan robot.Robot
-	runningRobots RunningRobots
+	logger          *zap.SugaredLogger
+	robotStorage    robot.Storage
+	orderStorage    order.Storage
+	accountStorage  account.Storage
+	robotsChan      chan robot.Robot
+	conns           []*grpc.ClientConn
+	client          streamer.TradingServiceClient
+	orders          streamer.OrderServiceClient
+	reconnectPolicy ReconnectPolicy
+	calendar        *calendar.Calendar
+	markInterval    time.Duration
+
+	mutex         *sync.Mutex
+	robots        map[int64]*runningRobot
+	subscriptions map[string]*subscription
+	timers        map[int64]*time.Timer // next start or stop of robots by their plans
+	marks         map[int64]float64     // unrealized PnL of robots to store
+	events        chan int64            // robots to reschedule
+	tickers       chan struct{}         // subscriptions are changed
+	closed        bool
+	streams       sync.WaitGroup
+	sending       sync.WaitGroup // calls of the order service
+
+	quit     chan struct{}
+	quitOnce sync.Once
+	runOnce  sync.Once // taken by Run, or by Stop if the engine never ran
+	done     chan struct{}
+	err      error
 }
 
-type RunningRobots struct {
-	robots map[int64]int
-	mutex  *sync.Mutex
+// runningRobot is the state of the robot the engine trades with
+type runningRobot struct {
+	ticker           string
+	strategy         strategy.Strategy
+	position         robot.Position
+	orderID          string       // active order, the robot waits for its fills
+	stopReason       string       // the robot breaks a risk limit and closes its position
+	closing          *order.Order // the placed order closing the position of the stopping robot
+	lastPrice        float64      // the price the position was marked at last
+	failedReconnects int64
 }
 
-func (b Background) Updater(r *robot.Robot, resp streamer.TradingService_PriceClient, conn *grpc.ClientConn) {
-	go func() {
-		for {
-			b.logger.Infof("updater")
-
-			price, err := resp.Recv()
-
-			if r.PlanEnd.Before(time.Now()) {
-				b.runningRobots.mutex.Lock()
-				delete(b.runningRobots.robots, r.RobotID)
-				b.runningRobots.mutex.Unlock()
-				b.logger.Infof("end")
-				conn.Close()
-
-				return
-			}
-
-			if err != nil {
-				b.runningRobots.mutex.Lock()
-				delete(b.runningRobots.robots, r.RobotID)
-				b.runningRobots.mutex.Unlock()
-				b.logger.Errorf("error while getting price: %v", err)
-
-				break
-			}
-
-			robotData, err := b.robotStorage.FindByID(r.RobotID)
-			if err != nil {
-				b.logger.Errorf("could not find user by ID: %v", err)
-
-				continue
-			}
-
-			updated := false
-
-			// fmt.Printf("price: %v\n", price)
-			// fmt.Printf("robotData: %v\n", robotData)
-
-			switch status := b.runningRobots.robots[r.RobotID]; status {
-			case Sold:
-				if robotData.BuyPrice >= price.BuyPrice {
-					robotData.FactYield -= price.BuyPrice
-					robotData.DealsCount++
-
-					b.logger.Infof("bought")
-					b.runningRobots.mutex.Lock()
-					b.runningRobots.robots[r.RobotID] = Bought
-					b.runningRobots.mutex.Unlock()
-
-					updated = true
-				}
-			case Bought:
-				if robotData.SellPrice <= price.SellPrice {
-					robotData.FactYield += price.SellPrice
-
-					b.logger.Infof("sold")
-					b.runningRobots.mutex.Lock()
-					b.runningRobots.robots[r.RobotID] = Sold
-					b.runningRobots.mutex.Unlock()
-
-					updated = true
-				}
-			}
-
-			if updated {
-				b.logger.Infof("updating")
-				b.robotsChan <- *robotData
-
-				if err := b.robotStorage.UpdateByID(robotData); err != nil {
-					b.logger.Errorf("can't update robot: %+v", err)
-				}
-			}
+// start begins trading of the robot from its stored position,
+// it is a no-op for already running robots
+func (b *Background) start(r *robot.Robot) {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return
+	}
+
+	if _, ok := b.robots[r.RobotID]; ok {
+		b.subscribe(r.Ticker, r.RobotID)
+		b.mutex.Unlock()
+
+		return
+	}
+	b.mutex.Unlock()
+
+	rr, err := b.newRunningRobot(r)
+	if err != nil {
+		b.logger.Errorf("can't start robot %d: %v", r.RobotID, err)
+
+		return
+	}
+
+	b.mutex.Lock()
+
+	if _, ok := b.robots[r.RobotID]; ok || b.closed {
+		b.mutex.Unlock()
+		return
+	}
+
+	b.robots[r.RobotID] = rr
+	b.subscribe(r.Ticker, r.RobotID)
+	robotsRunning.Inc()
+
+	status := robot.StatusRunning
+	if b.subscriptions[r.Ticker].disconnected {
+		status = robot.StatusDisconnected
+	}
+	b.mutex.Unlock()
+
+	b.logger.Infof("robot %d started on %s with position %+v", r.RobotID, r.Ticker, rr.position)
+	b.setStatus(r.RobotID, status, rr.failedReconnects)
+}
+
+func (b *Background) newRunningRobot(r *robot.Robot) (*runningRobot, error) {
+	strat, err := strategy.New(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create strategy")
+	}
+
+	// the robot could be stopped before its order was done, the order is cancelled
+	// instead of waiting for it and its fills are applied from the fill stream
+	o, err := b.orderStorage.FindActiveByRobotID(r.RobotID)
+
+	switch {
+	case err == nil:
+		b.cancelOrder(o)
+	case errors.Cause(err) != order.ErrNotFound:
+		return nil, errors.Wrap(err, "can't find active order")
+	}
+
+	pos, err := b.robotStorage.FindPositionByID(r.RobotID)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't restore position")
+	}
+
+	// the active robot with a stop reason was stopped before it closed its position
+	return &runningRobot{ticker: r.Ticker, strategy: strat, position: *pos, stopReason: r.StopReason, failedReconnects: r.FailedReconnects}, nil
+}
+
+// stop ends trading of the robot and closes the ticker stream if nobody else needs it
+func (b *Background) stop(id int64) {
+	b.mutex.Lock()
+
+	rr, ok := b.robots[id]
+	if !ok {
+		b.mutex.Unlock()
+		return
+	}
+
+	delete(b.robots, id)
+	delete(b.marks, id) // the final yield is stored below
+	b.unsubscribe(rr.ticker, id)
+	robotsRunning.Dec()
+	b.mutex.Unlock()
+
+	if rr.lastPrice > 0 {
+		b.storeFinalYield(id, rr.position, rr.lastPrice)
+	}
+
+	if rr.orderID != "" {
+		o, err := b.orderStorage.FindByID(rr.orderID)
+		if err != nil {
+			b.logger.Errorf("can't find order %s: %+v", rr.orderID, err)
+		} else {
+			b.cancelOrder(o)
 		}
-	}()
+	}
+
+	b.logger.Infof("robot %d stopped", id)
+	b.setStatus(id, robot.StatusStopped, rr.failedReconnects)
 }
 
-func (b Background) Listener(r *robot.Robot) {
-	conn, err := grpc.Dial("localhost:5000", grpc.WithInsecure())
+// storeFinalYield marks the position of the stopped robot at the last price
+func (b *Background) storeFinalYield(id int64, pos robot.Position, price float64) {
+	r, err := b.robotStorage.FindByID(id)
 	if err != nil {
-		b.logger.Errorf("can't connect to server: %v", err)
+		b.logger.Errorf("can't find robot %d: %+v", id, err)
 
 		return
 	}
-	// defer conn.Close()
 
-	client := streamer.NewTradingServiceClient(conn)
-	req := streamer.PriceRequest{Ticker: r.Ticker}
-	resp, err := client.Price(context.Background(), &req)
+	r.Mark(pos, price)
+
+	if err := b.robotStorage.UpdateUnrealizedPnLByID(id, r.UnrealizedPnL); err != nil {
+		b.logger.Errorf("can't store final yield of robot %d: %+v", id, err)
+	}
+}
+
+// setStatus stores the engine status of the robot and notifies websocket subscribers
+func (b *Background) setStatus(id int64, status string, failedReconnects int64) {
+	if err := b.robotStorage.UpdateStatusByID(id, status, failedReconnects); err != nil {
+		b.logger.Errorf("can't update status of robot %d: %+v", id, err)
 
+		return
+	}
+
+	r, err := b.robotStorage.FindByID(id)
 	if err != nil {
-		b.logger.Errorf("can't get price: %v", err)
+		b.logger.Errorf("can't find robot %d: %+v", id, err)
 
 		return
 	}
 
-	b.runningRobots.mutex.Lock()
-	b.runningRobots.robots[r.RobotID] = Sold
-	b.runningRobots.mutex.Unlock()
-	b.Updater(r, resp, conn)
+	b.robotsChan <- *r
 }
 
-func (b Background) RunActivateRobots() {
+// Run trades with active robots until ctx is cancelled or Stop is called,
+// then shuts the engine down and returns after everything is stored
+func (b *Background) Run(ctx context.Context) error {
+	run := false
+	b.runOnce.Do(func() {
+		run = true
+	})
+
+	if !run {
+		return ErrStopped
+	}
+
+	defer close(b.done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	go func() {
-		for {
-			robots, err := b.robotStorage.GetRobotsNeedToRun()
-			if err != nil {
-				b.logger.Errorf("error during getting robots list: %+v", err)
-				break
-			}
-
-			if len(robots) != 0 {
-				b.logger.Infof("%d robots are running", len(robots))
-			}
-
-			for _, v := range robots {
-				if _, ok := b.runningRobots.robots[v.RobotID]; !ok {
-					b.Listener(v)
-				}
-			}
-
-			time.Sleep(3 * time.Second) // nolint: gomnd
+		select {
+		case <-b.quit:
+			cancel()
+		case <-ctx.Done():
 		}
 	}()
+
+	b.streams.Add(3)
+
+	go b.listenFills(ctx)
+	go b.listenPrices(ctx)
+	go b.storeMarks(ctx)
+
+	b.scheduleAll()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.err = b.shutdown()
+			return b.err
+		case id := <-b.events:
+			b.reschedule(id)
+		}
+	}
+}
+
+// Stop asks the running engine to shut down and waits until it is done,
+// the engine that never ran only closes its connections
+func (b *Background) Stop() error {
+	b.quitOnce.Do(func() {
+		close(b.quit)
+	})
+
+	b.runOnce.Do(func() {
+		b.err = b.shutdown()
+		close(b.done)
+	})
+
+	<-b.done
+
+	return b.err
+}
+
+// shutdown refuses new robots, cancels their timers, waits for the streams to close and the ticks
+// and fills being processed to be stored, cancels active orders and marks
+// the robots stopped
+func (b *Background) shutdown() error {
+	b.logger.Info("stopping background engine")
+
+	b.mutex.Lock()
+	b.closed = true
+	b.cancelTimers()
+	b.mutex.Unlock()
+
+	b.streams.Wait()
+	b.sending.Wait()
+	b.flushMarks()
+
+	for _, id := range b.runningIDs() {
+		b.stop(id)
+	}
+
+	for _, conn := range b.conns {
+		if err := conn.Close(); err != nil {
+			return errors.Wrapf(err, "can't close connection to %s", conn.Target())
+		}
+	}
+
+	b.logger.Info("background engine stopped")
+
+	return nil
+}
+
+func (b *Background) runningIDs() []int64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	ids := make([]int64, 0, len(b.robots))
+	for id := range b.robots {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// NewBackground creates the robot engine sharing one connection to the price streamer
+// and one to the exchange, they are the same if the addresses are equal
+func NewBackground(logger *zap.SugaredLogger, robotStorage robot.Storage, orderStorage order.Storage,
+	accountStorage account.Storage, robotsChan chan robot.Robot, cfg Config) (*Background, error) {
+	conn, err := dial(cfg.StreamerAddr, cfg.Reconnect)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't connect to streamer %s", cfg.StreamerAddr)
+	}
+
+	conns := []*grpc.ClientConn{conn}
+	exchangeConn := conn
+
+	if cfg.ExchangeAddr != "" && cfg.ExchangeAddr != cfg.StreamerAddr {
+		exchangeConn, err = dial(cfg.ExchangeAddr, cfg.Reconnect)
+		if err != nil {
+			_ = conn.Close()
+			return nil, errors.Wrapf(err, "can't connect to exchange %s", cfg.ExchangeAddr)
+		}
+
+		conns = append(conns, exchangeConn)
+	}
+
+	if cfg.Calendar == nil {
+		cfg.Calendar = calendar.New(calendar.AlwaysOpen)
+	}
+
+	if cfg.MarkInterval <= 0 {
+		cfg.MarkInterval = DefaultMarkInterval
+	}
+
+	return &Background{
+		logger:          logger,
+		robotStorage:    robotStorage,
+		orderStorage:    orderStorage,
+		accountStorage:  accountStorage,
+		robotsChan:      robotsChan,
+		conns:           conns,
+		client:          streamer.NewTradingServiceClient(conn),
+		orders:          streamer.NewOrderServiceClient(exchangeConn),
+		reconnectPolicy: cfg.Reconnect,
+		calendar:        cfg.Calendar,
+		markInterval:    cfg.MarkInterval,
+		mutex:           new(sync.Mutex),
+		robots:          make(map[int64]*runningRobot),
+		subscriptions:   make(map[string]*subscription),
+		timers:          make(map[int64]*time.Timer),
+		marks:           make(map[int64]float64),
+		events:          make(chan int64),
+		tickers:         make(chan struct{}, 1),
+		quit:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}, nil
 }
-func NewBackground(logger *zap.SugaredLogger, robotStorage robot.Storage, robotsChan chan robot.Robot) {
-	result := Background{logger: logger, robotStorage: robotStorage, robotsChan: robotsChan}
-	result.runningRobots.robots = make(map[int64]int)
-	result.runningRobots.mutex = new(sync.Mutex)
-	result.RunActivateRobots()
+
+func dial(addr string, policy ReconnectPolicy) (*grpc.ClientConn, error) {
+	connectParams := grpc.ConnectParams{
+		Backoff: backoff.Config{
+			BaseDelay:  policy.InitialDelay,
+			Multiplier: policy.Multiplier,
+			Jitter:     policy.Jitter,
+			MaxDelay:   policy.MaxDelay,
+		},
+		MinConnectTimeout: minConnectTimeout,
+	}
+
+	return grpc.Dial(addr, grpc.WithInsecure(), grpc.WithConnectParams(connectParams))
 }
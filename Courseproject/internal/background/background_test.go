@@ -0,0 +1,755 @@
+package background
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"../calendar"
+	"../database"
+	"../exchange"
+	"../order"
+	"../pricestream"
+	"../robot"
+	"../strategy"
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// nolint: gomnd
+var testReconnectPolicy = ReconnectPolicy{
+	InitialDelay: 10 * time.Millisecond,
+	MaxDelay:     50 * time.Millisecond,
+	Multiplier:   2,
+	Jitter:       0.1,
+}
+
+// nolint: gomnd
+var testExchangeConfig = exchange.Config{FillInterval: time.Millisecond}
+
+// flip buys and sells on every tick
+type flip struct{}
+
+func (flip) Decide(pos robot.Position, _ strategy.Tick) strategy.Action {
+	if pos.Side == robot.Sold {
+		return strategy.Buy
+	}
+
+	return strategy.Sell
+}
+
+func init() {
+	strategy.Register("flip", func(*robot.Robot) (strategy.Strategy, error) {
+		return flip{}, nil
+	})
+}
+
+// nolint: gomnd
+func serveTestStreamer(t *testing.T, lis net.Listener, dropAfter int, cfg exchange.Config) *grpc.Server {
+	source, err := pricestream.NewRandomSource(pricestream.Config{Speed: 1, Seed: 1, StartPrice: 100, Volatility: 0.001, Interval: time.Millisecond})
+	require.NoError(t, err)
+
+	e := exchange.New(zap.NewNop().Sugar(), cfg)
+
+	srv := grpc.NewServer()
+	streamer.RegisterTradingServiceServer(srv, pricestream.NewServer(zap.NewNop().Sugar(), source, calendar.New(calendar.AlwaysOpen), dropAfter))
+	streamer.RegisterOrderServiceServer(srv, e)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	t.Cleanup(func() {
+		_ = e.Close()
+		srv.Stop()
+	})
+
+	return srv
+}
+
+func newTestStreamer(t *testing.T, dropAfter int) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	serveTestStreamer(t, lis, dropAfter, testExchangeConfig)
+
+	return lis.Addr().String()
+}
+
+// nolint: gomnd
+func newTestBackground(t *testing.T, storage robot.Storage, addr string) (*Background, chan robot.Robot) {
+	robotsChan := make(chan robot.Robot, 100)
+
+	b, err := NewBackground(zap.NewNop().Sugar(), storage, database.NewOrderStorage(), database.NewAccountStorage(), robotsChan, Config{StreamerAddr: addr, Reconnect: testReconnectPolicy})
+	require.NoError(t, err)
+
+	return b, robotsChan
+}
+
+// run starts the engine and stops it at the end of the test
+func run(t *testing.T, b *Background) {
+	go func() {
+		_ = b.Run(context.Background())
+	}()
+
+	t.Cleanup(func() {
+		require.NoError(t, b.Stop())
+	})
+}
+
+// drain discards robot updates the test is not interested in until the engine is done
+func drain(b *Background, robotsChan chan robot.Robot) {
+	go func() {
+		for {
+			select {
+			case <-robotsChan:
+			case <-b.done:
+				return
+			}
+		}
+	}()
+}
+
+// nolint: gomnd
+func newTestRobot(ticker string) *robot.Robot {
+	return &robot.Robot{
+		IsActive:  true,
+		Ticker:    ticker,
+		BuyPrice:  1000,
+		SellPrice: 1001,
+		PlanStart: time.Now().Add(-time.Minute),
+		PlanEnd:   time.Now().Add(time.Minute),
+	}
+}
+
+// nolint: gomnd
+func Test_FanOut(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	for i := 0; i < 3; i++ {
+		r.NoError(storage.Create(newTestRobot("SBER")))
+	}
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	run(t, b)
+
+	traded := make(map[int64]bool)
+
+	for len(traded) < 3 {
+		select {
+		case updated := <-robotsChan:
+			if updated.DealsCount > 0 {
+				r.Equal(int64(1), updated.DealsCount)
+				traded[updated.RobotID] = true
+			}
+		case <-time.After(5 * time.Second):
+			r.FailNow("robots haven't traded")
+		}
+	}
+
+	drain(b, robotsChan)
+
+	b.mutex.Lock()
+	r.Len(b.subscriptions, 1)
+	b.mutex.Unlock()
+
+	for id := int64(1); id <= 3; id++ {
+		r.NoError(storage.DeleteByID(id))
+		b.Schedule(id)
+	}
+
+	r.Eventually(func() bool {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		return len(b.subscriptions) == 0 && len(b.robots) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// nolint: gomnd
+func Test_RestorePosition(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.SellPrice = 1e6
+	r.NoError(storage.Create(tc))
+	r.NoError(storage.UpdatePositionByID(tc.RobotID, &robot.Position{Side: robot.Bought, Quantity: 1, EntryPrice: 99, EntryTime: time.Now()}))
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	run(t, b)
+
+	r.Eventually(func() bool {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		rr, ok := b.robots[tc.RobotID]
+
+		return ok && rr.position.Side == robot.Bought && rr.position.EntryPrice == 99
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+
+	for len(robotsChan) > 0 {
+		r.Zero((<-robotsChan).DealsCount, "robot holding shares must not buy again")
+	}
+}
+
+// nolint: gomnd
+func Test_DroppedStream(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.Strategy = "flip"
+	r.NoError(storage.Create(tc))
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 3))
+	drain(b, robotsChan)
+	run(t, b)
+
+	r.Eventually(func() bool {
+		rb, err := storage.FindByID(tc.RobotID)
+		r.NoError(err)
+
+		return rb.DealsCount >= 10
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// nolint: gomnd
+func Test_Reconnect(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	r.NoError(storage.Create(tc))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+
+	addr := lis.Addr().String()
+	srv := serveTestStreamer(t, lis, 0, testExchangeConfig)
+
+	b, robotsChan := newTestBackground(t, storage, addr)
+	drain(b, robotsChan)
+	run(t, b)
+
+	status := func(expected string, failedReconnects int64) func() bool {
+		return func() bool {
+			rb, err := storage.FindByID(tc.RobotID)
+			r.NoError(err)
+
+			return rb.Status == expected && rb.FailedReconnects >= failedReconnects
+		}
+	}
+
+	r.Eventually(status(robot.StatusRunning, 0), 5*time.Second, 10*time.Millisecond)
+
+	srv.Stop()
+	r.Eventually(status(robot.StatusDisconnected, 2), 5*time.Second, 10*time.Millisecond)
+
+	lis, err = net.Listen("tcp", addr)
+	r.NoError(err)
+	serveTestStreamer(t, lis, 0, testExchangeConfig)
+
+	r.Eventually(status(robot.StatusRunning, 2), 5*time.Second, 10*time.Millisecond)
+}
+
+// nolint: gomnd
+func Test_Shutdown(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	for i := 0; i < 2; i++ {
+		tc := newTestRobot("SBER")
+		tc.Strategy = "flip"
+		r.NoError(storage.Create(tc))
+	}
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	drain(b, robotsChan)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- b.Run(ctx)
+	}()
+
+	r.Eventually(func() bool {
+		rb, err := storage.FindByID(1)
+		r.NoError(err)
+
+		return rb.DealsCount > 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		r.NoError(err)
+	case <-time.After(5 * time.Second):
+		r.FailNow("engine hasn't stopped")
+	}
+
+	r.NoError(b.Stop())
+	r.Empty(b.robots)
+	r.Empty(b.subscriptions)
+
+	stored := make(map[int64]robot.Robot)
+
+	for id := int64(1); id <= 2; id++ {
+		rb, err := storage.FindByID(id)
+		r.NoError(err)
+		r.Equal(robot.StatusStopped, rb.Status)
+
+		stored[id] = *rb
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	for id, before := range stored {
+		rb, err := storage.FindByID(id)
+		r.NoError(err)
+		r.Equal(before, *rb, "robots must not change after shutdown")
+	}
+
+	b.start(newTestRobot("SBER"))
+	r.Empty(b.robots, "stopped engine must not take new robots")
+}
+
+// countingOrders counts orders sent by robots
+type countingOrders struct {
+	order.Storage
+	created int64
+}
+
+func (s *countingOrders) Create(o *order.Order) error {
+	atomic.AddInt64(&s.created, 1)
+	return s.Storage.Create(o)
+}
+
+func Test_StopWithoutRun(t *testing.T) {
+	r := require.New(t)
+
+	b, _ := newTestBackground(t, database.NewRobotStorage(), newTestStreamer(t, 0))
+
+	stopped := make(chan error)
+
+	go func() {
+		stopped <- b.Stop()
+	}()
+
+	select {
+	case err := <-stopped:
+		r.NoError(err)
+	case <-time.After(5 * time.Second):
+		r.FailNow("engine that never ran hasn't stopped")
+	}
+
+	r.Equal(ErrStopped, b.Run(context.Background()))
+}
+
+// nolint: gomnd
+func Test_RejectedOrders(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	r.NoError(storage.Create(tc))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	serveTestStreamer(t, lis, 0, exchange.Config{FillInterval: time.Millisecond, RejectRate: 1})
+
+	orders := &countingOrders{Storage: database.NewOrderStorage()}
+
+	b, robotsChan := newTestBackground(t, storage, lis.Addr().String())
+	b.orderStorage = orders
+	drain(b, robotsChan)
+	run(t, b)
+
+	r.Eventually(func() bool {
+		return atomic.LoadInt64(&orders.created) > 3
+	}, 5*time.Second, 10*time.Millisecond)
+
+	r.NoError(b.Stop())
+
+	rb, err := storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.Zero(rb.DealsCount)
+	r.Zero(rb.FactYield)
+
+	pos, err := storage.FindPositionByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(robot.Sold, pos.Side)
+
+	_, err = orders.FindActiveByRobotID(tc.RobotID)
+	r.Error(err, "rejected orders must not be active")
+}
+
+// slowOrders is the order service which takes time to place orders
+type slowOrders struct {
+	streamer.OrderServiceClient
+	delay time.Duration
+}
+
+func (s slowOrders) PlaceOrder(ctx context.Context, req *streamer.PlaceOrderRequest, opts ...grpc.CallOption) (*streamer.PlaceOrderResponse, error) {
+	time.Sleep(s.delay)
+	return s.OrderServiceClient.PlaceOrder(ctx, req, opts...)
+}
+
+// nolint: gomnd
+func Test_SlowOrders(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.Strategy = "flip"
+	r.NoError(storage.Create(tc))
+
+	b, _ := newTestBackground(t, storage, newTestStreamer(t, 0))
+	b.orders = slowOrders{OrderServiceClient: b.orders, delay: time.Second}
+	b.robots[tc.RobotID] = &runningRobot{ticker: "SBER", strategy: flip{}}
+
+	start := time.Now()
+	b.onTick(strategy.Tick{Ticker: "SBER", BuyPrice: 100, SellPrice: 100, Time: start})
+	r.Less(int64(time.Since(start)), int64(500*time.Millisecond), "ticks don't wait for the order service")
+
+	id := b.robots[tc.RobotID].orderID
+	r.NotEmpty(id)
+
+	b.sending.Wait()
+
+	o, err := b.orderStorage.FindByID(id)
+	r.NoError(err)
+	r.Equal(order.StatusNew, o.Status, "the order is placed in the background")
+}
+
+// nolint: gomnd
+func Test_PartialFills(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	r.NoError(storage.Create(tc))
+
+	b, _ := newTestBackground(t, storage, newTestStreamer(t, 0))
+	b.robots[tc.RobotID] = &runningRobot{ticker: "SBER", orderID: "buy"}
+
+	now := time.Now()
+	r.NoError(b.orderStorage.Create(&order.Order{OrderID: "buy", RobotID: tc.RobotID, Ticker: "SBER", Side: order.Buy, Quantity: 3, Price: 100, Status: order.StatusNew, CreatedAt: now}))
+
+	fill := func(id string, side streamer.OrderSide, quantity, filled int64, price float64, s streamer.OrderStatus) {
+		b.onFill(&streamer.Fill{OrderId: id, Ticker: "SBER", Side: side, Quantity: quantity, Price: price, Ts: ptypes.TimestampNow(), Status: s, FilledQuantity: filled})
+	}
+
+	fill("buy", streamer.OrderSide_BUY, 1, 1, 100, streamer.OrderStatus_PARTIALLY_FILLED)
+	fill("buy", streamer.OrderSide_BUY, 1, 1, 100, streamer.OrderStatus_PARTIALLY_FILLED) // repeated after reconnect
+	fill("buy", streamer.OrderSide_BUY, 2, 3, 103, streamer.OrderStatus_FILLED)
+
+	rr := b.robots[tc.RobotID]
+	r.Equal(robot.Bought, rr.position.Side)
+	r.Equal(int64(3), rr.position.Quantity)
+	r.InDelta(102, rr.position.EntryPrice, 1e-9)
+	r.Empty(rr.orderID)
+
+	rb, err := storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.Zero(rb.RealizedPnL)
+	r.InDelta(3, rb.UnrealizedPnL, 1e-9, "the position is marked at the last fill")
+	r.InDelta(3, rb.FactYield, 1e-9)
+
+	o, err := b.orderStorage.FindByID("buy")
+	r.NoError(err)
+	r.Equal(order.StatusFilled, o.Status)
+
+	fills, err := b.orderStorage.GetFillsByOrderID("buy")
+	r.NoError(err)
+	r.Len(fills, 2)
+
+	r.NoError(b.orderStorage.Create(&order.Order{OrderID: "sell", RobotID: tc.RobotID, Ticker: "SBER", Side: order.Sell, Quantity: 3, Price: 110, Status: order.StatusNew, CreatedAt: now}))
+	fill("sell", streamer.OrderSide_SELL, 3, 3, 110, streamer.OrderStatus_FILLED)
+
+	rb, err = storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(int64(1), rb.DealsCount)
+	r.InDelta(24, rb.RealizedPnL, 1e-9)
+	r.Zero(rb.UnrealizedPnL)
+	r.InDelta(24, rb.FactYield, 1e-9)
+
+	pos, err := storage.FindPositionByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(robot.Sold, pos.Side)
+
+	deals, err := database.NewDealStorage(b.orderStorage.(*database.OrderStorage)).GetByRobotID(tc.RobotID, 10, 0)
+	r.NoError(err)
+	r.Len(deals, 3, "every fill is a deal")
+	r.Equal(order.Sell, deals[0].Side)
+	r.Equal(int64(3), deals[0].Quantity)
+	r.Equal("buy", deals[2].OrderID)
+}
+
+// nolint: gomnd
+func Test_ReleaseAfterClose(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.IsActive = false
+	tc.OwnerUserID = 1
+	r.NoError(storage.Create(tc))
+	r.NoError(storage.UpdatePositionByID(tc.RobotID, &robot.Position{Side: robot.Bought, Quantity: 2, EntryPrice: 100}))
+
+	b, _ := newTestBackground(t, storage, newTestStreamer(t, 0))
+	r.NoError(b.accountStorage.Deposit(1, 300))
+	r.NoError(b.accountStorage.Reserve(1, tc.RobotID, 200))
+
+	fill := func(quantity, filled int64) {
+		b.onFill(&streamer.Fill{OrderId: "sell", Ticker: "SBER", Side: streamer.OrderSide_SELL, Quantity: quantity, Price: 110,
+			Ts: ptypes.TimestampNow(), Status: streamer.OrderStatus_PARTIALLY_FILLED, FilledQuantity: filled})
+	}
+
+	r.NoError(b.orderStorage.Create(&order.Order{OrderID: "sell", RobotID: tc.RobotID, Ticker: "SBER", Side: order.Sell, Quantity: 2, Price: 110, Status: order.StatusNew, CreatedAt: time.Now()}))
+
+	// the capital stays reserved until the position of the stopped robot is closed
+	fill(1, 1)
+
+	a, err := b.accountStorage.FindByUserID(1)
+	r.NoError(err)
+	r.Equal(100.0, a.Balance)
+	r.Equal(310.0, a.Reserved)
+
+	fill(1, 2)
+
+	a, err = b.accountStorage.FindByUserID(1)
+	r.NoError(err)
+	r.Equal(520.0, a.Balance)
+	r.Zero(a.Reserved)
+}
+
+// nolint: gomnd
+func Test_FillFee(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.CommissionType = robot.CommissionFixed
+	tc.Commission = 2
+	r.NoError(storage.Create(tc))
+
+	// the robot is not running, its position is loaded from the storage
+	b, _ := newTestBackground(t, storage, newTestStreamer(t, 0))
+
+	r.NoError(b.orderStorage.Create(&order.Order{OrderID: "buy", RobotID: tc.RobotID, Ticker: "SBER", Side: order.Buy, Quantity: 1, Price: 100, Status: order.StatusNew, CreatedAt: time.Now()}))
+	b.onFill(&streamer.Fill{OrderId: "buy", Ticker: "SBER", Side: streamer.OrderSide_BUY, Quantity: 1, Price: 100, Ts: ptypes.TimestampNow(), Status: streamer.OrderStatus_FILLED, FilledQuantity: 1})
+
+	deals, err := database.NewDealStorage(b.orderStorage.(*database.OrderStorage)).GetByRobotID(tc.RobotID, 10, 0)
+	r.NoError(err)
+	r.Len(deals, 1)
+	r.Equal(2.0, deals[0].Fee)
+
+	rb, err := storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(2.0, rb.Fees)
+	r.InDelta(-2, rb.RealizedPnL, 1e-9)
+}
+
+// nolint: gomnd
+func Test_MarkToMarket(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.RealizedPnL = 10
+	r.NoError(storage.Create(tc))
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	b.robots[tc.RobotID] = &runningRobot{ticker: "SBER", position: robot.Position{Side: robot.Bought, Quantity: 2, EntryPrice: 100}}
+
+	rb, err := storage.FindByID(tc.RobotID)
+	r.NoError(err)
+
+	b.mark(rb, strategy.Tick{Ticker: "SBER", BuyPrice: 96, SellPrice: 95})
+	r.InDelta(-10, b.marks[tc.RobotID], 1e-9)
+
+	rb, err = storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	b.mark(rb, strategy.Tick{Ticker: "SBER", BuyPrice: 95, SellPrice: 94})
+	r.Len(b.marks, 1, "ticks between stores keep the last PnL only")
+	r.InDelta(-12, b.marks[tc.RobotID], 1e-9)
+
+	b.flushMarks()
+	r.Empty(b.marks)
+
+	updated := <-robotsChan
+	r.InDelta(10, updated.RealizedPnL, 1e-9)
+	r.InDelta(-12, updated.UnrealizedPnL, 1e-9)
+	r.InDelta(-2, updated.FactYield, 1e-9, "a loss of the open position outweighs closed deals")
+
+	b.mark(&updated, strategy.Tick{Ticker: "SBER", BuyPrice: 95, SellPrice: 94})
+	r.Empty(b.marks, "the same quote must not store the robot again")
+}
+
+// nolint: gomnd
+func Test_Schedule(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	later := newTestRobot("SBER")
+	later.PlanStart = time.Now().Add(100 * time.Millisecond)
+	later.PlanEnd = time.Now().Add(300 * time.Millisecond)
+	r.NoError(storage.Create(later))
+
+	inactive := newTestRobot("SBER")
+	inactive.IsActive = false
+	r.NoError(storage.Create(inactive))
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	drain(b, robotsChan)
+	run(t, b)
+
+	running := func(id int64) func() bool {
+		return func() bool {
+			b.mutex.Lock()
+			defer b.mutex.Unlock()
+
+			_, ok := b.robots[id]
+
+			return ok
+		}
+	}
+
+	stopped := func(id int64) func() bool {
+		return func() bool {
+			return !running(id)()
+		}
+	}
+
+	r.Never(running(later.RobotID), 50*time.Millisecond, 5*time.Millisecond, "robot must wait for its plan")
+	r.Eventually(running(later.RobotID), time.Second, 5*time.Millisecond)
+	r.Eventually(stopped(later.RobotID), time.Second, 5*time.Millisecond, "robot must stop at plan end without ticks")
+
+	rb, err := storage.FindByID(later.RobotID)
+	r.NoError(err)
+	r.Equal(robot.StatusStopped, rb.Status)
+
+	inactive.IsActive = true
+	r.NoError(storage.UpdateByID(inactive))
+	b.Schedule(inactive.RobotID)
+	r.Eventually(running(inactive.RobotID), 100*time.Millisecond, time.Millisecond, "activated robot must start at once")
+
+	inactive.IsActive = false
+	r.NoError(storage.UpdateByID(inactive))
+	b.Schedule(inactive.RobotID)
+	r.Eventually(stopped(inactive.RobotID), 100*time.Millisecond, time.Millisecond, "deactivated robot must stop at once")
+}
+
+// nolint: gomnd
+func Test_StopLoss(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.StopLoss = 100
+	r.NoError(storage.Create(tc))
+	r.NoError(storage.UpdatePositionByID(tc.RobotID, &robot.Position{Side: robot.Bought, Quantity: 1, EntryPrice: 1000, EntryTime: time.Now()}))
+
+	b, robotsChan := newTestBackground(t, storage, newTestStreamer(t, 0))
+	drain(b, robotsChan)
+	run(t, b)
+
+	r.Eventually(func() bool {
+		rb, err := storage.FindByID(tc.RobotID)
+		return err == nil && !rb.IsActive
+	}, 5*time.Second, 10*time.Millisecond, "the robot must be deactivated")
+
+	pos, err := storage.FindPositionByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(robot.Sold, pos.Side, "the position is closed before the deactivation")
+
+	rb, err := storage.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(robot.StopReasonStopLoss, rb.StopReason)
+	r.Less(rb.RealizedPnL, -100.0)
+
+	a, err := b.accountStorage.FindByUserID(tc.OwnerUserID)
+	r.NoError(err)
+	r.Greater(a.Balance, 0.0, "the closing sell brings cash to the owner")
+
+	b.mutex.Lock()
+	_, ok := b.robots[tc.RobotID]
+	b.mutex.Unlock()
+	r.False(ok, "the robot must not trade after the stop")
+}
+
+// nolint: gomnd
+func Test_StopLossRejected(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	tc.StopLoss = 100
+	tc.OwnerUserID = 1
+	r.NoError(storage.Create(tc))
+	r.NoError(storage.UpdatePositionByID(tc.RobotID, &robot.Position{Side: robot.Bought, Quantity: 1, EntryPrice: 1000, EntryTime: time.Now()}))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	serveTestStreamer(t, lis, 0, exchange.Config{FillInterval: time.Millisecond, RejectRate: 0.5, Seed: 1})
+
+	b, robotsChan := newTestBackground(t, storage, lis.Addr().String())
+	r.NoError(b.accountStorage.Deposit(1, 1000))
+	r.NoError(b.accountStorage.Reserve(1, tc.RobotID, 1000))
+	drain(b, robotsChan)
+	run(t, b)
+
+	// rejected closing orders are sent again until the position is closed
+	r.Eventually(func() bool {
+		rb, err := storage.FindByID(tc.RobotID)
+		return err == nil && !rb.IsActive
+	}, 5*time.Second, 10*time.Millisecond, "the robot must be deactivated")
+
+	pos, err := storage.FindPositionByID(tc.RobotID)
+	r.NoError(err)
+	r.Equal(robot.Sold, pos.Side)
+
+	a, err := b.accountStorage.FindByUserID(1)
+	r.NoError(err)
+	r.Zero(a.Reserved, "the capital is returned once the position is closed")
+}
+
+// nolint: gomnd
+func Test_MarketClosed(t *testing.T) {
+	r := require.New(t)
+	storage := database.NewRobotStorage()
+
+	tc := newTestRobot("SBER")
+	r.NoError(storage.Create(tc))
+
+	closed := &calendar.Exchange{Name: "closed", Location: time.UTC, Close: 24 * time.Hour, Weekends: make(map[time.Weekday]bool)}
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		closed.Weekends[wd] = true
+	}
+
+	robotsChan := make(chan robot.Robot, 100)
+	b, err := NewBackground(zap.NewNop().Sugar(), storage, database.NewOrderStorage(), database.NewAccountStorage(), robotsChan,
+		Config{StreamerAddr: newTestStreamer(t, 0), Reconnect: testReconnectPolicy, Calendar: calendar.New(closed)})
+	r.NoError(err)
+
+	drain(b, robotsChan)
+	run(t, b)
+
+	r.Never(func() bool {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		return len(b.robots) > 0
+	}, 100*time.Millisecond, 5*time.Millisecond, "robot must not trade while the exchange is closed")
+}
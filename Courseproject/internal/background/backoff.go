@@ -0,0 +1,44 @@
+package background
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy describes delays between attempts to restore a broken price stream
+type ReconnectPolicy struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	Jitter       float64 // relative random deviation of the delay, from 0 to 1
+}
+
+// nolint: gomnd
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialDelay: 500 * time.Millisecond,
+	MaxDelay:     30 * time.Second,
+	Multiplier:   2,
+	Jitter:       0.2,
+}
+
+// Delay returns the pause before the attempt, attempts are counted from 1
+func (p ReconnectPolicy) Delay(attempt int) time.Duration {
+	return p.delay(attempt, rand.Float64()) // nolint: gosec
+}
+
+func (p ReconnectPolicy) delay(attempt int, random float64) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(p.InitialDelay) * math.Pow(p.Multiplier, float64(attempt-1))
+	delay *= 1 + p.Jitter*(2*random-1)
+
+	// the cap goes after the jitter so MaxDelay is never exceeded
+	if delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+
+	return time.Duration(delay)
+}
@@ -0,0 +1,28 @@
+package background
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// nolint: gomnd
+func Test_ReconnectPolicy(t *testing.T) {
+	r := require.New(t)
+	p := ReconnectPolicy{InitialDelay: time.Second, MaxDelay: 5 * time.Second, Multiplier: 2, Jitter: 0.5}
+
+	r.Equal(time.Second, p.delay(1, 0.5))
+	r.Equal(2*time.Second, p.delay(2, 0.5))
+	r.Equal(4*time.Second, p.delay(3, 0.5))
+	r.Equal(5*time.Second, p.delay(10, 0.5))
+	r.Equal(500*time.Millisecond, p.delay(1, 0))
+	r.Equal(1500*time.Millisecond, p.delay(1, 1))
+	r.Equal(5*time.Second, p.delay(3, 1), "jitter doesn't exceed the max delay")
+	r.Equal(2*time.Second, p.delay(3, 0))
+
+	for i := 0; i < 100; i++ {
+		d := p.Delay(3)
+		r.True(d >= 2*time.Second && d <= 5*time.Second)
+	}
+}
@@ -0,0 +1,69 @@
+package background
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const (
+	metricsNamespace = "fintech"
+	metricsSubsystem = "engine"
+)
+
+var (
+	robotsRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "robots_running",
+		Help:      "Robots trading in the engine.",
+	})
+	ticksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "ticks_total",
+		Help:      "Prices received from the streamer.",
+	}, []string{"ticker"})
+	tickDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "tick_duration_seconds",
+		Help:      "Time of fanning a price out to the robots of its ticker.",
+		Buckets:   prometheus.DefBuckets,
+	})
+	ordersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "orders_total",
+		Help:      "Orders sent to the exchange by their side and result.",
+	}, []string{"side", "result"})
+	dealsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "deals_total",
+		Help:      "Fills applied to robots by their side.",
+	}, []string{"side"})
+	riskStopsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "risk_stops_total",
+		Help:      "Robots stopped by their risk limits.",
+	}, []string{"reason"})
+	streamErrorsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "stream_errors_total",
+		Help:      "Breaks of the price and fill streams.",
+	}, []string{"stream"})
+)
+
+// Results of orders in ordersTotal
+const (
+	orderPlaced   = "placed"
+	orderRejected = "rejected"
+)
+
+// Streams in streamErrorsTotal
+const (
+	pricesStream = "prices"
+	fillsStream  = "fills"
+)
@@ -0,0 +1,352 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	"../order"
+	"../robot"
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const orderTimeout = 5 * time.Second
+
+var orderSides = map[string]streamer.OrderSide{
+	order.Buy:  streamer.OrderSide_BUY,
+	order.Sell: streamer.OrderSide_SELL,
+}
+
+var orderStatuses = map[streamer.OrderStatus]string{
+	streamer.OrderStatus_NEW:              order.StatusNew,
+	streamer.OrderStatus_PARTIALLY_FILLED: order.StatusPartiallyFilled,
+	streamer.OrderStatus_FILLED:           order.StatusFilled,
+	streamer.OrderStatus_CANCELLED:        order.StatusCancelled,
+	streamer.OrderStatus_REJECTED:         order.StatusRejected,
+}
+
+// sendOrder stores the order and places it on the exchange in the background,
+// a slow order service doesn't hold the ticks of other robots
+func (b *Background) sendOrder(o *order.Order) {
+	if err := b.orderStorage.Create(o); err != nil {
+		b.logger.Errorf("can't create order of robot %d: %+v", o.RobotID, err)
+		b.finishOrder(o)
+
+		return
+	}
+
+	b.send(func() {
+		b.placeOrder(o)
+	})
+}
+
+// send calls the order service in the background, shutdown waits for the calls
+func (b *Background) send(call func()) {
+	b.sending.Add(1)
+
+	go func() {
+		defer b.sending.Done()
+		call()
+	}()
+}
+
+// placeOrder sends the stored order to the exchange, the position
+// of the robot changes only when fills of the order come
+func (b *Background) placeOrder(o *order.Order) {
+	ctx, cancel := context.WithTimeout(context.Background(), orderTimeout)
+	defer cancel()
+
+	resp, err := b.orders.PlaceOrder(ctx, &streamer.PlaceOrderRequest{
+		OrderId:  o.OrderID,
+		Ticker:   o.Ticker,
+		Side:     orderSides[o.Side],
+		Quantity: o.Quantity,
+		Price:    o.Price,
+	})
+
+	switch {
+	case err != nil:
+		o.Status, o.RejectReason = order.StatusRejected, err.Error()
+	case resp.Status == streamer.OrderStatus_REJECTED:
+		o.Status, o.RejectReason = order.StatusRejected, resp.RejectReason
+	default:
+		ordersTotal.WithLabelValues(o.Side, orderPlaced).Inc()
+
+		// the robot could be stopped while the order was sent and couldn't cancel it
+		if !b.placed(o) {
+			b.cancelOrder(o)
+		}
+
+		return
+	}
+
+	ordersTotal.WithLabelValues(o.Side, orderRejected).Inc()
+
+	b.logger.Errorf("order %s of robot %d is rejected: %s", o.OrderID, o.RobotID, o.RejectReason)
+
+	o.UpdatedAt = time.Now()
+	if err := b.orderStorage.UpdateStatusByID(o); err != nil {
+		b.logger.Errorf("can't update order %s: %+v", o.OrderID, err)
+	}
+
+	b.finishOrder(o)
+}
+
+// cancelOrder cancels the rest of the order on the exchange, the fills made
+// before still come to the fill stream so only the status of the order is stored
+func (b *Background) cancelOrder(o *order.Order) {
+	ctx, cancel := context.WithTimeout(context.Background(), orderTimeout)
+	defer cancel()
+
+	resp, err := b.orders.CancelOrder(ctx, &streamer.CancelOrderRequest{OrderId: o.OrderID})
+
+	switch {
+	case status.Code(err) == codes.NotFound:
+		o.Status = order.StatusCancelled
+	case err != nil:
+		b.logger.Errorf("can't cancel order %s: %v", o.OrderID, err)
+		return
+	default:
+		o.Status = orderStatuses[resp.Status]
+	}
+
+	b.logger.Infof("order %s of robot %d is %s", o.OrderID, o.RobotID, o.Status)
+
+	o.UpdatedAt = time.Now()
+	if err := b.orderStorage.UpdateStatusByID(o); err != nil {
+		b.logger.Errorf("can't update order %s: %+v", o.OrderID, err)
+	}
+}
+
+// placed remembers the closing order of the robot stopped by risk to requote it,
+// it returns false if the robot isn't running anymore
+func (b *Background) placed(o *order.Order) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[o.RobotID]
+	if ok && rr.stopReason != "" && rr.orderID == o.OrderID {
+		closing := *o
+		rr.closing = &closing
+	}
+
+	return ok
+}
+
+// finishOrder lets the robot send new orders
+func (b *Background) finishOrder(o *order.Order) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if rr, ok := b.robots[o.RobotID]; ok && rr.orderID == o.OrderID {
+		rr.orderID = ""
+	}
+}
+
+// listenFills receives fills of all orders until ctx is cancelled, after reconnects
+// the exchange repeats fills since the last received one and they are applied once
+func (b *Background) listenFills(ctx context.Context) {
+	defer b.streams.Done()
+
+	var (
+		since   *timestamp.Timestamp
+		attempt int
+	)
+
+	for {
+		received, err := b.fillStream(ctx, &since)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if received {
+			attempt = 0
+		}
+
+		attempt++
+		delay := b.reconnectPolicy.Delay(attempt)
+
+		b.logger.Errorf("fill stream is broken: %v, reconnecting in %v", err, delay)
+		streamErrorsTotal.WithLabelValues(fillsStream).Inc()
+
+		if !wait(ctx, delay) {
+			return
+		}
+	}
+}
+
+func (b *Background) fillStream(ctx context.Context, since **timestamp.Timestamp) (bool, error) {
+	stream, err := b.orders.Fills(ctx, &streamer.FillsRequest{Since: *since})
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+
+	for {
+		f, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+
+		received = true
+		*since = f.Ts
+
+		b.onFill(f)
+	}
+}
+
+// onFill records the fill with the commission the robot charges for it and applies it
+// to the robot whether it is running or not, the stored fills are the deals of the robot
+func (b *Background) onFill(f *streamer.Fill) {
+	o, err := b.orderStorage.FindByID(f.OrderId)
+	if err != nil {
+		if errors.Cause(err) != order.ErrNotFound {
+			b.logger.Errorf("can't find order %s: %+v", f.OrderId, err)
+		}
+
+		return
+	}
+
+	if f.FilledQuantity <= o.FilledQuantity {
+		return // the fill is already applied
+	}
+
+	r, err := b.robotStorage.FindByID(o.RobotID)
+	if err != nil {
+		b.logger.Errorf("can't find robot %d: %+v", o.RobotID, err)
+		return
+	}
+
+	filledAt, err := ptypes.Timestamp(f.Ts)
+	if err != nil {
+		filledAt = time.Now()
+	}
+
+	fill := &order.Fill{
+		OrderID:  o.OrderID,
+		RobotID:  o.RobotID,
+		Ticker:   o.Ticker,
+		Side:     o.Side,
+		Quantity: f.Quantity,
+		Price:    f.Price,
+		FilledAt: filledAt,
+		Fee:      r.Fee(f.Price, f.Quantity),
+	}
+
+	if err := b.orderStorage.CreateFill(fill); err != nil {
+		b.logger.Errorf("can't create fill of order %s: %+v", o.OrderID, err)
+		return
+	}
+
+	o.FilledQuantity = f.FilledQuantity
+	if o.Active() {
+		o.Status = orderStatuses[f.Status]
+	}
+
+	o.UpdatedAt = filledAt
+
+	if err := b.orderStorage.UpdateByID(o); err != nil {
+		b.logger.Errorf("can't update order %s: %+v", o.OrderID, err)
+	}
+
+	b.logger.Infof("robot %d: %s %d %s at %v", o.RobotID, fill.Side, fill.Quantity, fill.Ticker, fill.Price)
+
+	b.applyFill(r, o, fill)
+	dealsTotal.WithLabelValues(fill.Side).Inc()
+}
+
+// applyFill changes the position, the yield and the cash of the robot
+func (b *Background) applyFill(r *robot.Robot, o *order.Order, f *order.Fill) {
+	var pos robot.Position
+
+	b.mutex.Lock()
+	rr, running := b.robots[r.RobotID]
+
+	if running {
+		rr.position, _ = r.Fill(rr.position, f)
+		pos = rr.position
+
+		delete(b.marks, r.RobotID) // the robot is stored with the fill below
+
+		if !o.Active() && rr.orderID == o.OrderID {
+			rr.orderID = ""
+		}
+	}
+
+	b.mutex.Unlock()
+
+	if !running {
+		p, err := b.robotStorage.FindPositionByID(r.RobotID)
+		if err != nil {
+			b.logger.Errorf("can't find position of robot %d: %+v", r.RobotID, err)
+			return
+		}
+
+		pos, _ = r.Fill(*p, f)
+	}
+
+	if err := b.robotStorage.UpdatePositionByID(r.RobotID, &pos); err != nil {
+		b.logger.Errorf("can't update position of robot %d: %+v", r.RobotID, err)
+	}
+
+	if err := b.accountStorage.Settle(r.OwnerUserID, r.RobotID, f.Cash()); err != nil {
+		b.logger.Errorf("can't settle fill of robot %d: %+v", r.RobotID, err)
+	}
+
+	b.storePnL(r)
+	b.releaseIfFlat(r.RobotID)
+}
+
+// storePnL stores the yield of the filled robot and notifies websocket subscribers,
+// the rest of the robot could be changed since it was read and isn't written
+func (b *Background) storePnL(r *robot.Robot) {
+	if err := b.robotStorage.UpdatePnLByID(r); err != nil {
+		b.logger.Errorf("can't update pnl of robot %d: %+v", r.RobotID, err)
+
+		return
+	}
+
+	stored, err := b.robotStorage.FindByID(r.RobotID)
+	if err != nil {
+		b.logger.Errorf("can't find robot %d: %+v", r.RobotID, err)
+
+		return
+	}
+
+	b.logger.Infof("updating robot %d", r.RobotID)
+	b.robotsChan <- *stored
+}
+
+// releaseIfFlat returns the capital of the robot to its owner once the robot doesn't
+// trade and its position is closed. Both the engine closing the position and the
+// deactivation call it after their change, so one of them sees the other one
+func (b *Background) releaseIfFlat(id int64) {
+	r, err := b.robotStorage.FindByID(id)
+	if err != nil {
+		b.logger.Errorf("can't find robot %d: %+v", id, err)
+		return
+	}
+
+	if r.IsActive && !r.DeletedAt.Valid {
+		return
+	}
+
+	pos, err := b.robotStorage.FindPositionByID(id)
+	if err != nil {
+		b.logger.Errorf("can't find position of robot %d: %+v", id, err)
+		return
+	}
+
+	if pos.Side == robot.Bought {
+		return
+	}
+
+	if err := b.accountStorage.Release(id); err != nil {
+		b.logger.Errorf("can't release capital of robot %d: %+v", id, err)
+	}
+}
@@ -0,0 +1,111 @@
+package background
+
+import (
+	"time"
+
+	"../robot"
+)
+
+// Schedule makes the engine look at the robot again after it has been changed:
+// the robot starts or stops at once and waits for the start or the end of its plan
+// or of the trading session
+func (b *Background) Schedule(id int64) {
+	select {
+	case b.events <- id:
+	case <-b.done:
+	}
+}
+
+// scheduleAll plans every active robot when the engine starts
+func (b *Background) scheduleAll() {
+	robots, err := b.robotStorage.GetAllRobots()
+	if err != nil {
+		b.logger.Errorf("can't get robots: %+v", err)
+		return
+	}
+
+	for _, r := range robots {
+		if r.IsActive {
+			b.plan(r)
+		}
+	}
+}
+
+// reschedule plans the robot by its stored state, robots which can't be read are stopped
+func (b *Background) reschedule(id int64) {
+	r, err := b.robotStorage.FindByID(id)
+	if err != nil {
+		b.logger.Errorf("can't find robot %d: %+v", id, err)
+		b.cancelTimer(id)
+		b.stop(id)
+
+		return
+	}
+
+	b.plan(r)
+}
+
+// plan runs the robot during its plan while its exchange trades and sets
+// the timer for the next change
+func (b *Background) plan(r *robot.Robot) {
+	now := time.Now()
+
+	switch {
+	case !r.IsActive || r.DeletedAt.Valid || !now.Before(r.PlanEnd):
+		b.cancelTimer(r.RobotID)
+		b.stop(r.RobotID)
+	case now.Before(r.PlanStart):
+		b.stop(r.RobotID)
+		b.setTimer(r.RobotID, r.PlanStart.Sub(now))
+	default:
+		open, change := b.calendar.ForTicker(r.Ticker).NextChange(now)
+		if open {
+			b.start(r)
+		} else {
+			b.stop(r.RobotID)
+		}
+
+		next := r.PlanEnd
+		if !change.IsZero() && change.Before(next) {
+			next = change
+		}
+
+		b.setTimer(r.RobotID, next.Sub(now))
+	}
+}
+
+func (b *Background) setTimer(id int64, d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if t, ok := b.timers[id]; ok {
+		t.Stop()
+	}
+
+	if b.closed {
+		delete(b.timers, id)
+		return
+	}
+
+	b.timers[id] = time.AfterFunc(d, func() {
+		b.Schedule(id)
+	})
+}
+
+func (b *Background) cancelTimer(id int64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if t, ok := b.timers[id]; ok {
+		t.Stop()
+		delete(b.timers, id)
+	}
+}
+
+// cancelTimers stops all timers, b.mutex must be held
+func (b *Background) cancelTimers() {
+	for id, t := range b.timers {
+		t.Stop()
+		delete(b.timers, id)
+	}
+}
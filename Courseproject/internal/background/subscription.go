@@ -0,0 +1,265 @@
+package background
+
+import (
+	"context"
+	"time"
+
+	"../robot"
+	streamer "../streamer"
+)
+
+// subscription is a ticker of the price stream shared by all its robots
+type subscription struct {
+	ticker       string
+	robots       map[int64]struct{}
+	disconnected bool
+}
+
+// subscribe adds the robot to the ticker adding the ticker to the price stream
+// if needed, b.mutex must be held
+func (b *Background) subscribe(ticker string, id int64) {
+	sub, ok := b.subscriptions[ticker]
+	if !ok {
+		sub = &subscription{ticker: ticker, robots: make(map[int64]struct{})}
+		b.subscriptions[ticker] = sub
+
+		b.logger.Infof("subscribing to %s", ticker)
+		b.tickersChanged()
+	}
+
+	sub.robots[id] = struct{}{}
+}
+
+// unsubscribe removes the robot from the ticker and removes the ticker from
+// the price stream after its last robot, b.mutex must be held
+func (b *Background) unsubscribe(ticker string, id int64) {
+	sub, ok := b.subscriptions[ticker]
+	if !ok {
+		return
+	}
+
+	delete(sub.robots, id)
+
+	if len(sub.robots) == 0 {
+		delete(b.subscriptions, ticker)
+		b.logger.Infof("unsubscribed from %s", ticker)
+		b.tickersChanged()
+	}
+}
+
+// tickersChanged wakes up the sender of ticker changes, it never blocks
+func (b *Background) tickersChanged() {
+	select {
+	case b.tickers <- struct{}{}:
+	default:
+	}
+}
+
+// listenPrices keeps the price stream alive until ctx is done reconnecting after failures,
+// the stream is opened when the first robot needs prices
+func (b *Background) listenPrices(ctx context.Context) {
+	defer b.streams.Done()
+
+	if !b.waitSubscriptions(ctx) {
+		return
+	}
+
+	attempt := 0
+
+	for {
+		received, err := b.stream(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if received {
+			attempt = 0
+		}
+
+		attempt++
+		delay := b.reconnectPolicy.Delay(attempt)
+
+		b.logger.Errorf("price stream is broken: %v, reconnecting in %v", err, delay)
+		streamErrorsTotal.WithLabelValues(pricesStream).Inc()
+		b.disconnected(!received)
+
+		if !wait(ctx, delay) {
+			return
+		}
+	}
+}
+
+// waitSubscriptions blocks until some robot needs prices or ctx is done
+func (b *Background) waitSubscriptions(ctx context.Context) bool {
+	for {
+		b.mutex.Lock()
+		n := len(b.subscriptions)
+		b.mutex.Unlock()
+
+		if n > 0 {
+			return true
+		}
+
+		select {
+		case <-b.tickers:
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// stream reads prices until the stream breaks and reports whether any price has been received
+func (b *Background) stream(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := b.client.Subscribe(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	sendDone := make(chan struct{})
+
+	go func() {
+		defer close(sendDone)
+
+		if err := b.sendTickers(ctx, stream); err != nil {
+			b.logger.Errorf("can't change tickers of price stream: %v", err)
+			cancel()
+		}
+	}()
+
+	defer func() {
+		cancel()
+		<-sendDone
+	}()
+
+	received := make(map[string]bool)
+
+	for {
+		price, err := stream.Recv()
+		if err != nil {
+			return len(received) > 0, err
+		}
+
+		if !received[price.Ticker] {
+			received[price.Ticker] = true
+
+			b.connected(price.Ticker)
+		}
+
+		b.onTick(newTick(price))
+	}
+}
+
+// sendTickers keeps tickers of the stream equal to the subscribed ones until ctx is done
+func (b *Background) sendTickers(ctx context.Context, stream streamer.TradingService_SubscribeClient) error {
+	sent := make(map[string]bool)
+
+	for {
+		if req := b.tickersRequest(sent); len(req.Add) > 0 || len(req.Remove) > 0 {
+			if err := stream.Send(req); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-b.tickers:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// tickersRequest returns the change from the sent tickers to the subscribed ones and applies it to sent
+func (b *Background) tickersRequest(sent map[string]bool) *streamer.SubscribeRequest {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	req := &streamer.SubscribeRequest{}
+
+	for ticker := range b.subscriptions {
+		if !sent[ticker] {
+			req.Add = append(req.Add, ticker)
+			sent[ticker] = true
+		}
+	}
+
+	for ticker := range sent {
+		if _, ok := b.subscriptions[ticker]; !ok {
+			req.Remove = append(req.Remove, ticker)
+			delete(sent, ticker)
+		}
+	}
+
+	return req
+}
+
+// disconnected marks robots of every ticker, failed shows that the stream
+// has not delivered any price since the last reconnect
+func (b *Background) disconnected(failed bool) {
+	failedReconnects := make(map[int64]int64)
+
+	b.mutex.Lock()
+	for _, sub := range b.subscriptions {
+		b.countReconnects(sub, failed && sub.disconnected, failedReconnects)
+		sub.disconnected = true
+	}
+	b.mutex.Unlock()
+
+	b.setStatuses(failedReconnects, robot.StatusDisconnected)
+}
+
+func (b *Background) connected(ticker string) {
+	b.mutex.Lock()
+
+	sub, ok := b.subscriptions[ticker]
+	if !ok || !sub.disconnected {
+		b.mutex.Unlock()
+		return
+	}
+
+	sub.disconnected = false
+
+	failedReconnects := make(map[int64]int64, len(sub.robots))
+	b.countReconnects(sub, false, failedReconnects)
+	b.mutex.Unlock()
+
+	b.logger.Infof("price stream of %s is restored", ticker)
+	b.setStatuses(failedReconnects, robot.StatusRunning)
+}
+
+// countReconnects collects failed reconnects of the robots of the ticker counting
+// one more if failed, b.mutex must be held
+func (b *Background) countReconnects(sub *subscription, failed bool, failedReconnects map[int64]int64) {
+	for id := range sub.robots {
+		rr, ok := b.robots[id]
+		if !ok {
+			continue
+		}
+
+		if failed {
+			rr.failedReconnects++
+		}
+
+		failedReconnects[id] = rr.failedReconnects
+	}
+}
+
+func (b *Background) setStatuses(failedReconnects map[int64]int64, status string) {
+	for id, n := range failedReconnects {
+		b.setStatus(id, status, n)
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,282 @@
+package background
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"../order"
+	"../robot"
+	"../strategy"
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func newTick(price *streamer.PriceResponse) strategy.Tick {
+	ts, err := ptypes.Timestamp(price.Ts)
+	if err != nil {
+		ts = time.Now()
+	}
+
+	return strategy.Tick{
+		Ticker:     price.Ticker,
+		BuyPrice:   price.BuyPrice,
+		SellPrice:  price.SellPrice,
+		Time:       ts,
+		Bids:       newLevels(price.Bids),
+		Asks:       newLevels(price.Asks),
+		LastPrice:  price.LastPrice,
+		LastVolume: price.LastVolume,
+	}
+}
+
+func newLevels(levels []*streamer.PriceLevel) []strategy.Level {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	result := make([]strategy.Level, len(levels))
+	for i, l := range levels {
+		result[i] = strategy.Level{Price: l.Price, Size: l.Size}
+	}
+
+	return result
+}
+
+// onTick fans the tick out to every running robot on its ticker
+func (b *Background) onTick(tick strategy.Tick) {
+	ticksTotal.WithLabelValues(tick.Ticker).Inc()
+
+	timer := prometheus.NewTimer(tickDuration)
+	defer timer.ObserveDuration()
+
+	robots, err := b.robotStorage.GetWorkingRobotsByTicker(tick.Ticker)
+	if err != nil {
+		b.logger.Errorf("can't get robots of %s: %+v", tick.Ticker, err)
+
+		return
+	}
+
+	for _, r := range robots {
+		b.mark(r, tick)
+
+		if reason := b.checkRisk(r); reason != "" {
+			b.stopByRisk(r, reason)
+		}
+
+		if b.closedByRisk(r) {
+			b.finishRiskStop(r.RobotID)
+			continue
+		}
+
+		b.requote(r, tick)
+
+		if o := b.decide(r, tick); o != nil {
+			b.sendOrder(o)
+		}
+	}
+}
+
+// checkRisk returns the limit the marked robot breaks, robots waiting
+// for fills are checked on the ticks after the fills
+func (b *Background) checkRisk(r *robot.Robot) string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[r.RobotID]
+	if !ok || rr.orderID != "" || rr.stopReason != "" {
+		return ""
+	}
+
+	return r.RiskBreach(rr.position)
+}
+
+// stopByRisk records the reason and makes the robot close its position, the robot
+// sells on the next ticks until it is flat and is deactivated then
+func (b *Background) stopByRisk(r *robot.Robot, reason string) {
+	b.mutex.Lock()
+	rr, ok := b.robots[r.RobotID]
+
+	if ok {
+		rr.stopReason = reason
+	}
+	b.mutex.Unlock()
+
+	if !ok {
+		return
+	}
+
+	b.logger.Infof("robot %d breaks its %s limit, fact yield %v", r.RobotID, reason, r.FactYield)
+	riskStopsTotal.WithLabelValues(reason).Inc()
+
+	if err := b.robotStorage.UpdateStopReasonByID(r.RobotID, reason); err != nil {
+		b.logger.Errorf("can't store stop reason of robot %d: %+v", r.RobotID, err)
+	}
+}
+
+// closedByRisk shows whether the robot stopped by risk has closed its position
+func (b *Background) closedByRisk(r *robot.Robot) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[r.RobotID]
+
+	return ok && rr.stopReason != "" && rr.orderID == "" && rr.position.Side != robot.Bought
+}
+
+// finishRiskStop deactivates the robot with the closed position and returns its capital
+func (b *Background) finishRiskStop(id int64) {
+	if err := b.robotStorage.DeactivateByID(id); err != nil {
+		b.logger.Errorf("can't deactivate robot %d: %+v", id, err)
+	}
+
+	b.cancelTimer(id)
+	b.stop(id)
+	b.releaseIfFlat(id)
+}
+
+// requote cancels the closing order of the robot stopped by risk when the price
+// falls below the order, the rest of the position is offered at the next tick
+func (b *Background) requote(r *robot.Robot, tick strategy.Tick) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[r.RobotID]
+	if !ok || rr.closing == nil || rr.orderID != rr.closing.OrderID || tick.SellPrice >= rr.closing.Price {
+		return
+	}
+
+	o := rr.closing
+	rr.closing = nil // the order is being cancelled
+
+	b.send(func() {
+		b.cancelOrder(o)
+
+		if !o.Active() {
+			b.finishOrder(o)
+			return
+		}
+
+		b.mutex.Lock()
+		if rr, ok := b.robots[o.RobotID]; ok && rr.orderID == o.OrderID {
+			rr.closing = o // cancel it again at the next tick
+		}
+		b.mutex.Unlock()
+	})
+}
+
+// mark revalues the open position of the robot at the tick, the unrealized PnL
+// is stored later by storeMarks when it differs from the stored one
+func (b *Background) mark(r *robot.Robot, tick strategy.Tick) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[r.RobotID]
+	if !ok {
+		return
+	}
+
+	stored := r.UnrealizedPnL
+	rr.lastPrice = tick.SellPrice
+	r.Mark(rr.position, tick.SellPrice)
+
+	if r.UnrealizedPnL != stored {
+		b.marks[r.RobotID] = r.UnrealizedPnL
+	}
+}
+
+// storeMarks stores the marked PnL of robots every mark interval until ctx is done,
+// a robot marked by many ticks is stored once with its last PnL
+func (b *Background) storeMarks(ctx context.Context) {
+	defer b.streams.Done()
+
+	ticker := time.NewTicker(b.markInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			b.flushMarks()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *Background) flushMarks() {
+	b.mutex.Lock()
+	marks := b.marks
+	b.marks = make(map[int64]float64)
+	b.mutex.Unlock()
+
+	for id, pnl := range marks {
+		b.setUnrealizedPnL(id, pnl)
+	}
+}
+
+func (b *Background) setUnrealizedPnL(id int64, pnl float64) {
+	if err := b.robotStorage.UpdateUnrealizedPnLByID(id, pnl); err != nil {
+		b.logger.Errorf("can't update pnl of robot %d: %+v", id, err)
+
+		return
+	}
+
+	r, err := b.robotStorage.FindByID(id)
+	if err != nil {
+		b.logger.Errorf("can't find robot %d: %+v", id, err)
+
+		return
+	}
+
+	b.robotsChan <- *r
+}
+
+// decide applies the decision of the robot strategy, it returns the order
+// to send when the robot wants to make a deal and has no active order
+func (b *Background) decide(r *robot.Robot, tick strategy.Tick) *order.Order {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	rr, ok := b.robots[r.RobotID]
+	if !ok || rr.orderID != "" {
+		return nil
+	}
+
+	var o *order.Order
+	if rr.stopReason != "" {
+		o = closingOrder(rr.position, tick)
+	} else {
+		o = strategy.NewOrder(rr.strategy, r, rr.position, tick)
+	}
+
+	if o == nil {
+		return nil
+	}
+
+	newOrder(r, o, tick)
+	rr.orderID = o.OrderID
+
+	b.logger.Infof("robot %d wants to %s %d %s at %v", r.RobotID, o.Side, o.Quantity, r.Ticker, o.Price)
+
+	return o
+}
+
+// closingOrder sells the whole position at the tick, it is nil for the robot without a position
+func closingOrder(pos robot.Position, tick strategy.Tick) *order.Order {
+	if pos.Side != robot.Bought {
+		return nil
+	}
+
+	return &order.Order{Side: order.Sell, Quantity: pos.Quantity, Price: tick.SellPrice}
+}
+
+// newOrder fills the order of the robot sent on the tick
+func newOrder(r *robot.Robot, o *order.Order, tick strategy.Tick) *order.Order {
+	o.RobotID, o.Ticker, o.Status = r.RobotID, r.Ticker, order.StatusNew
+	o.QuoteTime, o.CreatedAt = tick.Time, time.Now()
+	o.OrderID = fmt.Sprintf("%d-%d", r.RobotID, o.CreatedAt.UnixNano())
+	o.UpdatedAt = o.CreatedAt
+
+	return o
+}
@@ -0,0 +1,105 @@
+package backtest
+
+import (
+	"time"
+
+	"../candles"
+	"../deal"
+	"../order"
+	"../pricestream"
+	"../robot"
+	"../strategy"
+	"github.com/pkg/errors"
+)
+
+var ErrNoCandles = errors.New("no candles of the robot ticker")
+
+// Point is the fact yield of the robot at the close of a candle
+type Point struct {
+	Time      time.Time `json:"time"`
+	FactYield float64   `json:"fact_yield"`
+}
+
+// Result of the robot trading on historical candles
+type Result struct {
+	Deals         []deal.Deal `json:"deals"`
+	DealsCount    int64       `json:"deals_count"`
+	RealizedPnL   float64     `json:"realized_pnl"`
+	UnrealizedPnL float64     `json:"unrealized_pnl"`
+	FactYield     float64     `json:"fact_yield"`
+	Fees          float64     `json:"fees"`
+	StopReason    string      `json:"stop_reason"`
+	Equity        []Point     `json:"equity"`
+}
+
+// Run replays the candles of the robot ticker through its strategy the way
+// the engine trades, every order is filled at once at its price
+func Run(r robot.Robot, series []candles.Candle, spread float64) (*Result, error) {
+	series = candles.ByTicker(series, r.Ticker)
+	if len(series) == 0 {
+		return nil, errors.Wrapf(ErrNoCandles, "ticker %q", r.Ticker)
+	}
+
+	s, err := strategy.New(&r)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't create strategy")
+	}
+
+	r.DealsCount, r.RealizedPnL, r.UnrealizedPnL, r.FactYield, r.Fees, r.PeakYield = 0, 0, 0, 0, 0, 0
+
+	var (
+		pos      robot.Position
+		result   Result
+		interval = pricestream.CandleInterval(series)
+	)
+
+	for _, c := range series {
+		for _, q := range pricestream.CandleQuotes(c, interval, pricestream.Config{Spread: spread}) {
+			if result.StopReason != "" {
+				break
+			}
+
+			tick := strategy.Tick{Ticker: q.Ticker, BuyPrice: q.BuyPrice, SellPrice: q.SellPrice, Time: q.Time}
+			r.Mark(pos, tick.SellPrice)
+
+			o := strategy.NewOrder(s, &r, pos, tick)
+
+			// the engine closes the position and deactivates the robot breaking its limits
+			if result.StopReason = r.RiskBreach(pos); result.StopReason != "" {
+				o = nil
+				if pos.Side == robot.Bought {
+					o = &order.Order{Side: order.Sell, Quantity: pos.Quantity, Price: tick.SellPrice}
+				}
+			}
+
+			if o == nil {
+				continue
+			}
+
+			f := &order.Fill{RobotID: r.RobotID, Ticker: r.Ticker, Side: o.Side, Quantity: o.Quantity, Price: o.Price, FilledAt: tick.Time}
+			pos, f.Fee = r.Fill(pos, f)
+
+			result.Deals = append(result.Deals, deal.Deal{
+				DealID:     int64(len(result.Deals)) + 1,
+				RobotID:    r.RobotID,
+				Ticker:     r.Ticker,
+				Side:       f.Side,
+				Price:      f.Price,
+				Quantity:   f.Quantity,
+				Fee:        f.Fee,
+				QuoteTime:  tick.Time,
+				ExecutedAt: f.FilledAt,
+			})
+		}
+
+		result.Equity = append(result.Equity, Point{Time: c.Time, FactYield: r.FactYield})
+	}
+
+	result.DealsCount = r.DealsCount
+	result.RealizedPnL = r.RealizedPnL
+	result.UnrealizedPnL = r.UnrealizedPnL
+	result.FactYield = r.FactYield
+	result.Fees = r.Fees
+
+	return &result, nil
+}
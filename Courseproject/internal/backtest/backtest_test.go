@@ -0,0 +1,92 @@
+package backtest
+
+import (
+	"strings"
+	"testing"
+
+	"../candles"
+	"../order"
+	"../robot"
+	"github.com/stretchr/testify/require"
+)
+
+const testCandles = "SBER,2019-01-30T07:00:00Z,100,101,95,96\n" +
+	"AAPL,2019-01-30T07:00:00Z,163,163.44,156.25,162.68\n" +
+	"SBER,2019-01-30T07:05:00Z,96,111,96,110\n" +
+	"SBER,2019-01-30T07:10:00Z,110,110,96,98\n"
+
+// nolint: gomnd
+func Test_Run(t *testing.T) {
+	r := require.New(t)
+
+	series, err := candles.ReadCSV(strings.NewReader(testCandles))
+	r.NoError(err)
+
+	res, err := Run(robot.Robot{Ticker: "SBER", BuyPrice: 96, SellPrice: 110}, series, 0)
+	r.NoError(err)
+
+	r.Len(res.Deals, 3)
+	r.Equal(order.Buy, res.Deals[0].Side)
+	r.Equal(95.0, res.Deals[0].Price)
+	r.Equal(order.Sell, res.Deals[1].Side)
+	r.Equal(111.0, res.Deals[1].Price)
+	r.Equal(order.Buy, res.Deals[2].Side)
+	r.Equal(96.0, res.Deals[2].Price)
+
+	r.Equal(int64(2), res.DealsCount)
+	r.InDelta(16, res.RealizedPnL, 1e-9)
+	r.InDelta(2, res.UnrealizedPnL, 1e-9, "the last buy is marked at the close")
+	r.InDelta(18, res.FactYield, 1e-9)
+
+	r.Len(res.Equity, 3)
+	r.InDelta(1, res.Equity[0].FactYield, 1e-9)
+	r.InDelta(16, res.Equity[1].FactYield, 1e-9)
+
+	_, err = Run(robot.Robot{Ticker: "AMZN", BuyPrice: 96, SellPrice: 110}, series, 0)
+	r.Error(err)
+}
+
+// nolint: gomnd
+func Test_RunSizing(t *testing.T) {
+	r := require.New(t)
+
+	series, err := candles.ReadCSV(strings.NewReader(testCandles))
+	r.NoError(err)
+
+	rb := robot.Robot{Ticker: "SBER", BuyPrice: 96, SellPrice: 110, LotSize: 10, MaxPosition: 20,
+		CommissionType: robot.CommissionFixed, Commission: 1}
+
+	res, err := Run(rb, series, 0)
+	r.NoError(err)
+
+	r.Len(res.Deals, 4)
+	r.Equal(int64(10), res.Deals[0].Quantity)
+	r.Equal(95.0, res.Deals[0].Price)
+	r.Equal(int64(10), res.Deals[1].Quantity, "the robot adds a lot up to the max position")
+	r.Equal(96.0, res.Deals[1].Price)
+	r.Equal(order.Sell, res.Deals[2].Side)
+	r.Equal(int64(20), res.Deals[2].Quantity)
+	r.Equal(1.0, res.Deals[2].Fee)
+
+	r.InDelta(4, res.Fees, 1e-9)
+	r.InDelta(20*(111-95.5)-4, res.RealizedPnL, 1e-9)
+	r.InDelta(10*(98-96)-1, res.UnrealizedPnL, 1e-9, "the closing commission is taken into account")
+	r.InDelta(res.RealizedPnL+res.UnrealizedPnL, res.FactYield, 1e-9)
+}
+
+// nolint: gomnd
+func Test_RunTakeProfit(t *testing.T) {
+	r := require.New(t)
+
+	series, err := candles.ReadCSV(strings.NewReader(testCandles))
+	r.NoError(err)
+
+	res, err := Run(robot.Robot{Ticker: "SBER", BuyPrice: 96, SellPrice: 200, TakeProfit: 5}, series, 0)
+	r.NoError(err)
+
+	r.Equal(robot.StopReasonTakeProfit, res.StopReason)
+	r.Len(res.Deals, 2, "the robot doesn't trade after the stop")
+	r.Equal(order.Sell, res.Deals[1].Side)
+	r.Zero(res.UnrealizedPnL)
+	r.GreaterOrEqual(res.RealizedPnL, 5.0)
+}
@@ -0,0 +1,261 @@
+package calendar
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	day       = 24 * time.Hour
+	dateFmt   = "2006-01-02"
+	clockFmt  = "15:04"
+	maxSearch = 366 // days to look for the next session
+)
+
+var (
+	ErrUnknownExchange = errors.New("unknown exchange")
+	ErrWrongConfig     = errors.New("wrong calendar config")
+)
+
+// Exchange is the trading schedule of an exchange: one session a day
+// in its time zone except weekends and holidays
+type Exchange struct {
+	Name     string
+	Location *time.Location
+	Open     time.Duration // since the local midnight
+	Close    time.Duration // since the local midnight, up to 24h
+	Weekends map[time.Weekday]bool
+	Holidays map[string]bool // local dates in 2006-01-02 format
+}
+
+// AlwaysOpen trades every day around the clock, it is used without a calendar config
+var AlwaysOpen = &Exchange{Name: "always-open", Location: time.UTC, Close: day}
+
+// DaySession is the 07:00-24:00 UTC session of Lesson3 candles, the candle builder
+// uses it without a calendar config
+var DaySession = &Exchange{Name: "day-session", Location: time.UTC, Open: 7 * time.Hour, Close: day}
+
+// Session returns the session of the local day of t, ok is false
+// if the exchange doesn't trade that day
+func (e *Exchange) Session(t time.Time) (start, end time.Time, ok bool) {
+	t = t.In(e.Location)
+
+	if e.Weekends[t.Weekday()] || e.Holidays[t.Format(dateFmt)] {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return e.clock(t, e.Open), e.clock(t, e.Close), true
+}
+
+// clock returns the wall time of the local day of t
+func (e *Exchange) clock(t time.Time, d time.Duration) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, int(d/time.Minute), 0, 0, e.Location)
+}
+
+// IsOpen reports whether the exchange trades at t
+func (e *Exchange) IsOpen(t time.Time) bool {
+	start, end, ok := e.Session(t)
+
+	return ok && !t.Before(start) && t.Before(end)
+}
+
+// NextChange reports whether the exchange trades at t and returns the time it
+// closes or opens next, the time is zero if the exchange never opens again
+func (e *Exchange) NextChange(t time.Time) (open bool, at time.Time) {
+	for i := 0; i <= maxSearch; i++ {
+		start, end, ok := e.Session(t.AddDate(0, 0, i))
+
+		switch {
+		case !ok || !t.Before(end):
+		case t.Before(start):
+			return false, start
+		default:
+			return true, end
+		}
+	}
+
+	return false, time.Time{}
+}
+
+// Calendar finds exchanges of tickers
+type Calendar struct {
+	exchanges map[string]*Exchange
+	tickers   map[string]*Exchange
+	dflt      *Exchange
+}
+
+// New creates the calendar trading all tickers on the exchange
+func New(dflt *Exchange) *Calendar {
+	return &Calendar{
+		exchanges: map[string]*Exchange{dflt.Name: dflt},
+		tickers:   make(map[string]*Exchange),
+		dflt:      dflt,
+	}
+}
+
+// Exchange finds the exchange by its name
+func (c *Calendar) Exchange(name string) (*Exchange, error) {
+	e, ok := c.exchanges[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownExchange, "exchange %q", name)
+	}
+
+	return e, nil
+}
+
+// ForTicker returns the exchange listing the ticker or the default one
+func (c *Calendar) ForTicker(ticker string) *Exchange {
+	if e, ok := c.tickers[ticker]; ok {
+		return e
+	}
+
+	return c.dflt
+}
+
+type config struct {
+	Default   string           `json:"default"`
+	Exchanges []exchangeConfig `json:"exchanges"`
+}
+
+type exchangeConfig struct {
+	Name     string   `json:"name"`
+	Timezone string   `json:"timezone"`
+	Open     string   `json:"open"`
+	Close    string   `json:"close"`
+	Weekends []string `json:"weekends"`
+	Holidays []string `json:"holidays"`
+	Tickers  []string `json:"tickers"`
+}
+
+// LoadFile reads the calendar config in JSON
+func LoadFile(path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't open calendar")
+	}
+
+	defer f.Close()
+
+	return Load(f)
+}
+
+// Load reads the calendar config in JSON, the default exchange trades
+// tickers not listed by any exchange
+func Load(r io.Reader) (*Calendar, error) {
+	var cfg config
+
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, errors.Wrap(err, "can't decode calendar")
+	}
+
+	c := &Calendar{exchanges: make(map[string]*Exchange), tickers: make(map[string]*Exchange)}
+
+	for _, ec := range cfg.Exchanges {
+		e, err := newExchange(ec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "exchange %q", ec.Name)
+		}
+
+		if _, ok := c.exchanges[e.Name]; ok {
+			return nil, errors.Wrapf(ErrWrongConfig, "exchange %q is repeated", e.Name)
+		}
+
+		c.exchanges[e.Name] = e
+
+		for _, ticker := range ec.Tickers {
+			if other, ok := c.tickers[ticker]; ok {
+				return nil, errors.Wrapf(ErrWrongConfig, "ticker %q is listed by %q and %q", ticker, other.Name, e.Name)
+			}
+
+			c.tickers[ticker] = e
+		}
+	}
+
+	dflt, err := c.Exchange(cfg.Default)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't find default exchange")
+	}
+
+	c.dflt = dflt
+
+	return c, nil
+}
+
+func newExchange(cfg exchangeConfig) (*Exchange, error) {
+	if cfg.Name == "" {
+		return nil, errors.Wrap(ErrWrongConfig, "name is required")
+	}
+
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load timezone")
+	}
+
+	e := &Exchange{
+		Name:     cfg.Name,
+		Location: loc,
+		Weekends: make(map[time.Weekday]bool),
+		Holidays: make(map[string]bool),
+	}
+
+	if e.Open, err = parseClock(cfg.Open); err != nil {
+		return nil, errors.Wrap(err, "can't parse open")
+	}
+
+	if e.Close, err = parseClock(cfg.Close); err != nil {
+		return nil, errors.Wrap(err, "can't parse close")
+	}
+
+	if e.Open >= e.Close {
+		return nil, errors.Wrap(ErrWrongConfig, "open must be before close")
+	}
+
+	for _, name := range cfg.Weekends {
+		wd, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+
+		e.Weekends[wd] = true
+	}
+
+	for _, date := range cfg.Holidays {
+		t, err := time.Parse(dateFmt, date)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse holiday %q", date)
+		}
+
+		e.Holidays[t.Format(dateFmt)] = true
+	}
+
+	return e, nil
+}
+
+// parseClock parses time of the day from 00:00 to 24:00
+func parseClock(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return day, nil
+	}
+
+	t, err := time.Parse(clockFmt, s)
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(wd.String(), name) {
+			return wd, nil
+		}
+	}
+
+	return 0, errors.Wrapf(ErrWrongConfig, "unknown weekday %q", name)
+}
@@ -0,0 +1,101 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCalendar = `{
+	"default": "MOEX",
+	"exchanges": [
+		{
+			"name": "MOEX",
+			"timezone": "Europe/Moscow",
+			"open": "10:00",
+			"close": "18:45",
+			"weekends": ["Saturday", "Sunday"],
+			"holidays": ["2020-01-01", "2020-01-02"]
+		},
+		{
+			"name": "NYSE",
+			"timezone": "America/New_York",
+			"open": "09:30",
+			"close": "16:00",
+			"weekends": ["saturday", "sunday"],
+			"tickers": ["AAPL"]
+		}
+	]
+}`
+
+// nolint: gomnd
+func Test_Load(t *testing.T) {
+	r := require.New(t)
+
+	c, err := Load(strings.NewReader(testCalendar))
+	r.NoError(err)
+
+	r.Equal("NYSE", c.ForTicker("AAPL").Name)
+	r.Equal("MOEX", c.ForTicker("SBER").Name)
+
+	_, err = c.Exchange("LSE")
+	r.Error(err)
+
+	for _, bad := range []string{
+		`{"default": "MOEX", "exchanges": []}`,
+		`{"default": "X", "exchanges": [{"name": "X", "timezone": "UTC", "open": "18:00", "close": "10:00"}]}`,
+		`{"default": "X", "exchanges": [{"name": "X", "timezone": "UTC", "open": "10:00", "close": "18:00", "weekends": ["Funday"]}]}`,
+		`{"default": "X", "exchanges": [{"name": "X", "timezone": "Mars/Olympus", "open": "10:00", "close": "18:00"}]}`,
+	} {
+		_, err = Load(strings.NewReader(bad))
+		r.Error(err, bad)
+	}
+}
+
+// nolint: gomnd
+func Test_NextChange(t *testing.T) {
+	r := require.New(t)
+
+	c, err := Load(strings.NewReader(testCalendar))
+	r.NoError(err)
+
+	moex := c.ForTicker("SBER")
+	msk := moex.Location
+
+	// Tuesday morning before the open
+	open, at := moex.NextChange(time.Date(2020, 1, 14, 9, 0, 0, 0, msk))
+	r.False(open)
+	r.Equal(time.Date(2020, 1, 14, 10, 0, 0, 0, msk), at)
+
+	open, at = moex.NextChange(time.Date(2020, 1, 14, 12, 0, 0, 0, msk))
+	r.True(open)
+	r.Equal(time.Date(2020, 1, 14, 18, 45, 0, 0, msk), at)
+	r.True(moex.IsOpen(time.Date(2020, 1, 14, 12, 0, 0, 0, msk)))
+
+	// Friday evening waits for Monday
+	open, at = moex.NextChange(time.Date(2020, 1, 17, 19, 0, 0, 0, msk))
+	r.False(open)
+	r.Equal(time.Date(2020, 1, 20, 10, 0, 0, 0, msk), at)
+
+	// holidays and the weekend after them
+	open, at = moex.NextChange(time.Date(2019, 12, 31, 19, 0, 0, 0, msk))
+	r.False(open)
+	r.Equal(time.Date(2020, 1, 3, 10, 0, 0, 0, msk), at)
+	r.False(moex.IsOpen(time.Date(2020, 1, 2, 12, 0, 0, 0, msk)))
+
+	open, at = AlwaysOpen.NextChange(time.Date(2020, 1, 1, 3, 0, 0, 0, time.UTC))
+	r.True(open)
+	r.Equal(time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), at)
+}
+
+// nolint: gomnd
+func Test_DaySession(t *testing.T) {
+	r := require.New(t)
+
+	sat := time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC)
+	r.False(DaySession.IsOpen(sat.Add(6 * time.Hour)))
+	r.True(DaySession.IsOpen(sat.Add(7*time.Hour)), "Lesson3 candles trade every day")
+	r.True(DaySession.IsOpen(sat.Add(23*time.Hour + 59*time.Minute)))
+}
@@ -0,0 +1,91 @@
+package candles
+
+import (
+	"math"
+	"time"
+
+	"../calendar"
+)
+
+// Trade is a deal of the ticker the candles are built from
+type Trade struct {
+	Ticker string
+	Price  float64
+	Volume int64
+	Time   time.Time
+}
+
+// Aggregator builds candles of the interval from trades coming in time order,
+// candles start at the session start of the exchange and every interval after it,
+// trades out of sessions are skipped
+type Aggregator struct {
+	interval time.Duration
+	exchange *calendar.Exchange
+	candles  map[string]*Candle // unfinished candles of tickers
+}
+
+func NewAggregator(interval time.Duration, exchange *calendar.Exchange) *Aggregator {
+	return &Aggregator{interval: interval, exchange: exchange, candles: make(map[string]*Candle)}
+}
+
+// Add puts the trade into the candle of its ticker and returns the candle
+// finished by the trade, ok is false while the candle goes on
+func (a *Aggregator) Add(t Trade) (finished Candle, ok bool) {
+	start, in := a.candleStart(t.Time)
+	if !in {
+		return Candle{}, false
+	}
+
+	c, exists := a.candles[t.Ticker]
+
+	switch {
+	case !exists:
+	case c.Time.Equal(start):
+		c.High = math.Max(c.High, t.Price)
+		c.Low = math.Min(c.Low, t.Price)
+		c.Close = t.Price
+		c.Volume += t.Volume
+
+		return Candle{}, false
+	case start.Before(c.Time):
+		// late trades of finished candles are dropped
+		return Candle{}, false
+	default:
+		finished, ok = *c, true
+	}
+
+	a.candles[t.Ticker] = &Candle{
+		Ticker: t.Ticker,
+		Time:   start,
+		Open:   t.Price,
+		High:   t.Price,
+		Low:    t.Price,
+		Close:  t.Price,
+		Volume: t.Volume,
+	}
+
+	return finished, ok
+}
+
+// Flush returns unfinished candles and forgets them
+func (a *Aggregator) Flush() []Candle {
+	result := make([]Candle, 0, len(a.candles))
+
+	for ticker, c := range a.candles {
+		result = append(result, *c)
+		delete(a.candles, ticker)
+	}
+
+	return result
+}
+
+// candleStart returns the start of the candle the time belongs to,
+// in is false out of the session
+func (a *Aggregator) candleStart(t time.Time) (time.Time, bool) {
+	begin, end, ok := a.exchange.Session(t)
+	if !ok || t.Before(begin) || !t.Before(end) {
+		return time.Time{}, false
+	}
+
+	return begin.Add(t.Sub(begin).Truncate(a.interval)).UTC(), true
+}
@@ -0,0 +1,100 @@
+package candles
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Candle is an OHLC bar of a ticker starting at Time
+type Candle struct {
+	Ticker string
+	Time   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64 // traded volume, csv candles don't have it
+}
+
+const csvFields = 6
+
+// ReadCSV reads candles in the format written by the candles builder:
+// ticker, time (RFC3339), open, high, low, close
+func ReadCSV(r io.Reader) ([]Candle, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = csvFields
+
+	var result []Candle
+
+	for {
+		line, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, errors.Wrap(err, "can't read candles")
+		}
+
+		c, err := parseCandle(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "can't parse line %v", line)
+		}
+
+		result = append(result, c)
+	}
+
+	return result, nil
+}
+
+func parseCandle(line []string) (Candle, error) {
+	t, err := time.Parse(time.RFC3339Nano, line[1])
+	if err != nil {
+		return Candle{}, errors.Wrap(err, "error while parsing time")
+	}
+
+	var prices [4]float64
+
+	for i := range prices {
+		prices[i], err = strconv.ParseFloat(line[i+2], 64)
+		if err != nil {
+			return Candle{}, errors.Wrap(err, "error while parsing price")
+		}
+	}
+
+	return Candle{
+		Ticker: line[0],
+		Time:   t,
+		Open:   prices[0],
+		High:   prices[1],
+		Low:    prices[2],
+		Close:  prices[3],
+	}, nil
+}
+
+// ByTicker returns candles of the ticker keeping their order
+func ByTicker(candles []Candle, ticker string) []Candle {
+	var result []Candle
+
+	for _, c := range candles {
+		if c.Ticker == ticker {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
+// Path returns the prices passed by the candle in the most probable order:
+// a rising candle goes through its low first, a falling one through its high
+func (c Candle) Path() []float64 {
+	if c.Close >= c.Open {
+		return []float64{c.Open, c.Low, c.High, c.Close}
+	}
+
+	return []float64{c.Open, c.High, c.Low, c.Close}
+}
@@ -0,0 +1,72 @@
+package candles
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"../calendar"
+	"github.com/stretchr/testify/require"
+)
+
+// nolint: gomnd
+func Test_ReadCSV(t *testing.T) {
+	r := require.New(t)
+	in := "AAPL,2019-01-30T07:00:00Z,163,163.44,156.25,162.68\n" +
+		"SBER,2019-01-30T07:00:00Z,213.8,214.14,213.1,213.17\n"
+
+	candles, err := ReadCSV(strings.NewReader(in))
+	r.NoError(err)
+	r.Len(candles, 2)
+	r.Equal("SBER", candles[1].Ticker)
+	r.Equal(214.14, candles[1].High)
+	r.Len(ByTicker(candles, "AAPL"), 1)
+	r.Equal([]float64{213.8, 214.14, 213.1, 213.17}, candles[1].Path())
+
+	_, err = ReadCSV(strings.NewReader("AAPL,yesterday,1,2,3,4\n"))
+	r.Error(err)
+}
+
+// nolint: gomnd
+func Test_Aggregator(t *testing.T) {
+	r := require.New(t)
+	day := time.Date(2019, 1, 30, 0, 0, 0, 0, time.UTC)
+	a := NewAggregator(5*time.Minute, &calendar.Exchange{Location: time.UTC, Open: 7 * time.Hour, Close: 24 * time.Hour})
+
+	trade := func(ticker string, price float64, at time.Duration) (Candle, bool) {
+		return a.Add(Trade{Ticker: ticker, Price: price, Volume: 10, Time: day.Add(at)})
+	}
+
+	_, ok := trade("SBER", 200, 6*time.Hour+59*time.Minute)
+	r.False(ok, "trades before the session are skipped")
+
+	for i, price := range []float64{213.8, 214.14, 213.1, 213.17} {
+		_, ok = trade("SBER", price, 7*time.Hour+time.Duration(i)*time.Minute)
+		r.False(ok)
+	}
+
+	_, ok = trade("AAPL", 163, 7*time.Hour+2*time.Minute)
+	r.False(ok)
+
+	c, ok := trade("SBER", 213.75, 7*time.Hour+5*time.Minute)
+	r.True(ok)
+	r.Equal(Candle{Ticker: "SBER", Time: day.Add(7 * time.Hour), Open: 213.8, High: 214.14, Low: 213.1, Close: 213.17, Volume: 40}, c)
+
+	_, ok = trade("SBER", 1, 7*time.Hour+4*time.Minute)
+	r.False(ok, "late trades are dropped")
+
+	c, ok = trade("SBER", 214, 7*time.Hour+17*time.Minute)
+	r.True(ok)
+	r.Equal(day.Add(7*time.Hour+5*time.Minute), c.Time)
+	r.Equal(213.75, c.Close)
+
+	rest := a.Flush()
+	r.Len(rest, 2)
+	r.Empty(a.Flush())
+
+	for _, c := range rest {
+		if c.Ticker == "SBER" {
+			r.Equal(day.Add(7*time.Hour+15*time.Minute), c.Time)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package database
+
+import (
+	"sync"
+	"time"
+
+	"../account"
+)
+
+var _ account.Storage = &AccountStorage{}
+
+type reservation struct {
+	userID int64
+	amount float64
+}
+
+type AccountStorage struct {
+	accounts     map[int64]*account.Account
+	reservations map[int64]reservation
+	mutex        sync.RWMutex
+}
+
+func NewAccountStorage() *AccountStorage {
+	return &AccountStorage{
+		accounts:     make(map[int64]*account.Account),
+		reservations: make(map[int64]reservation),
+	}
+}
+
+func (s *AccountStorage) FindByUserID(userID int64) (*account.Account, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	a := account.Account{UserID: userID}
+	if stored, ok := s.accounts[userID]; ok {
+		a = *stored
+	}
+
+	for _, r := range s.reservations {
+		if r.userID == userID {
+			a.Reserved += r.amount
+		}
+	}
+
+	return &a, nil
+}
+
+// add changes the balance of the user, s.mutex must be held
+func (s *AccountStorage) add(userID int64, amount float64) {
+	a, ok := s.accounts[userID]
+	if !ok {
+		a = &account.Account{UserID: userID}
+		s.accounts[userID] = a
+	}
+
+	a.Balance = account.Round(a.Balance + amount)
+	a.UpdatedAt = time.Now()
+}
+
+func (s *AccountStorage) balance(userID int64) float64 {
+	if a, ok := s.accounts[userID]; ok {
+		return a.Balance
+	}
+
+	return 0
+}
+
+func (s *AccountStorage) Deposit(userID int64, amount float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.add(userID, amount)
+
+	return nil
+}
+
+func (s *AccountStorage) Withdraw(userID int64, amount float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.balance(userID) < amount {
+		return account.ErrInsufficientFunds
+	}
+
+	s.add(userID, -amount)
+
+	return nil
+}
+
+func (s *AccountStorage) Reserve(userID, robotID int64, amount float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	amount = account.Round(amount)
+
+	if s.balance(userID) < amount {
+		return account.ErrInsufficientFunds
+	}
+
+	s.add(userID, -amount)
+
+	r := s.reservations[robotID]
+	r.userID = userID
+	r.amount = account.Round(r.amount + amount)
+	s.reservations[robotID] = r
+
+	return nil
+}
+
+func (s *AccountStorage) Release(robotID int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.reservations[robotID]
+	if !ok {
+		return nil
+	}
+
+	delete(s.reservations, robotID)
+	s.add(r.userID, r.amount)
+
+	return nil
+}
+
+func (s *AccountStorage) Settle(userID, robotID int64, cash float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.reservations[robotID]
+	if !ok {
+		s.add(userID, cash)
+		return nil
+	}
+
+	r.amount = account.Round(r.amount + cash)
+	if r.amount < 0 {
+		s.add(userID, r.amount)
+		r.amount = 0
+	}
+
+	s.reservations[robotID] = r
+
+	return nil
+}
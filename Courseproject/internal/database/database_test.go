@@ -1,12 +1,20 @@
 package database
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"../account"
+	"../order"
 	"../robot"
+	"../session"
+	"../user"
 
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
 )
 
 // nolint: gomnd
@@ -36,3 +44,207 @@ func Test_DeacivateRobot(t *testing.T) {
 
 	r.NoError(err)
 }
+
+// nolint: gomnd
+func Test_RobotUpdatePnL(t *testing.T) {
+	r := require.New(t)
+	s := NewRobotStorage()
+
+	r.NoError(s.Create(&robot.Robot{IsActive: true}))
+
+	read, err := s.FindByID(1)
+	r.NoError(err)
+
+	// the robot is deactivated while its fill is applied
+	r.NoError(s.DeactivateByID(1))
+
+	read.DealsCount, read.FactYield = 1, 10
+	r.NoError(s.UpdatePnLByID(read))
+
+	stored, err := s.FindByID(1)
+	r.NoError(err)
+	r.False(stored.IsActive, "the yield doesn't bring the robot back")
+	r.Equal(int64(1), stored.DealsCount)
+	r.Equal(10.0, stored.FactYield)
+}
+
+// nolint: gomnd
+func Test_OrderUpdateStatus(t *testing.T) {
+	r := require.New(t)
+	s := NewOrderStorage()
+
+	r.NoError(s.Create(&order.Order{OrderID: "buy", Quantity: 3, Status: order.StatusNew}))
+
+	read, err := s.FindByID("buy")
+	r.NoError(err)
+
+	// a fill comes while the order is cancelled
+	filled := *read
+	filled.FilledQuantity, filled.Status = 1, order.StatusPartiallyFilled
+	r.NoError(s.UpdateByID(&filled))
+
+	read.Status = order.StatusCancelled
+	r.NoError(s.UpdateStatusByID(read))
+
+	stored, err := s.FindByID("buy")
+	r.NoError(err)
+	r.Equal(order.StatusCancelled, stored.Status)
+	r.Equal(int64(1), stored.FilledQuantity, "the fill isn't applied again when it is resent")
+}
+
+// nolint: gomnd
+func Test_DealsPaging(t *testing.T) {
+	r := require.New(t)
+	orders := NewOrderStorage()
+	s := NewDealStorage(orders)
+
+	quoted := time.Now().Add(-time.Minute)
+	r.NoError(orders.Create(&order.Order{OrderID: "buy", RobotID: 1, QuoteTime: quoted}))
+
+	for i := 1; i <= 5; i++ {
+		r.NoError(orders.CreateFill(&order.Fill{OrderID: "buy", RobotID: 1, Price: float64(i), Fee: 1}))
+		r.NoError(orders.CreateFill(&order.Fill{OrderID: "sell", RobotID: 2, Price: float64(i)}))
+	}
+
+	deals, err := s.GetByRobotID(1, 2, 0)
+	r.NoError(err)
+	r.Len(deals, 2)
+	r.Equal(5.0, deals[0].Price, "newest deals go first")
+	r.Equal(4.0, deals[1].Price)
+	r.Equal(1.0, deals[0].Fee)
+	r.Equal(quoted, deals[0].QuoteTime, "deals keep the quote time of the order")
+
+	deals, err = s.GetByRobotID(1, 2, 4)
+	r.NoError(err)
+	r.Len(deals, 1)
+	r.Equal(1.0, deals[0].Price)
+}
+
+// nolint: gomnd
+func Test_RobotPlanYield(t *testing.T) {
+	r := require.New(t)
+	s := NewRobotStorage()
+
+	tc := &robot.Robot{BuyPrice: 100, SellPrice: 110, LotSize: 2, CommissionType: robot.CommissionFixed, Commission: 1}
+	r.NoError(s.Create(tc))
+
+	stored, err := s.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.InDelta(18, stored.PlanYield, 1e-9)
+
+	stored.SellPrice = 120
+	r.NoError(s.UpdateByID(stored))
+
+	stored, err = s.FindByID(tc.RobotID)
+	r.NoError(err)
+	r.InDelta(38, stored.PlanYield, 1e-9)
+}
+
+// nolint: gomnd
+func Test_Accounts(t *testing.T) {
+	r := require.New(t)
+	s := NewAccountStorage()
+
+	r.NoError(s.Deposit(1, 1000))
+	r.Equal(account.ErrInsufficientFunds, s.Reserve(1, 10, 1500))
+	r.NoError(s.Reserve(1, 10, 600))
+
+	a, err := s.FindByUserID(1)
+	r.NoError(err)
+	r.InDelta(400, a.Balance, 1e-9)
+	r.InDelta(600, a.Reserved, 1e-9)
+
+	r.NoError(s.Settle(1, 10, -650), "a buy beyond the reservation is paid from the balance")
+	r.NoError(s.Settle(1, 10, 700))
+	r.NoError(s.Release(10))
+
+	a, err = s.FindByUserID(1)
+	r.NoError(err)
+	r.InDelta(1050, a.Balance, 1e-9)
+	r.Zero(a.Reserved)
+
+	r.NoError(s.Settle(1, 10, 50), "fills of robots without a reservation go to the balance")
+	r.Equal(account.ErrInsufficientFunds, s.Withdraw(1, 2000))
+	r.NoError(s.Withdraw(1, 1100))
+
+	a, err = s.FindByUserID(1)
+	r.NoError(err)
+	r.Zero(a.Balance)
+}
+
+// nolint: gomnd
+func Test_SessionJanitor(t *testing.T) {
+	r := require.New(t)
+	s := NewSessionStorage()
+	now := time.Now()
+
+	for i := 0; i < 5; i++ {
+		r.NoError(s.Create(&session.Session{SessionID: fmt.Sprintf("expired%d", i), UserID: 1, ValidUntil: now.Add(-time.Minute)}))
+	}
+
+	r.NoError(s.Create(&session.Session{SessionID: "valid", UserID: 1, ValidUntil: now.Add(time.Minute)}))
+
+	j, err := session.NewJanitor(zap.NewNop().Sugar(), s, session.JanitorConfig{Interval: time.Minute, BatchSize: 2})
+	r.NoError(err)
+
+	n, err := j.Purge(context.Background(), now)
+	r.NoError(err)
+	r.Equal(int64(5), n)
+
+	_, err = s.FindByToken("expired0")
+	r.Error(err)
+
+	_, err = s.FindByToken("valid")
+	r.NoError(err)
+}
+
+func Test_SessionDeleteFamily(t *testing.T) {
+	r := require.New(t)
+	s := NewSessionStorage()
+	validUntil := time.Now().Add(time.Minute)
+
+	r.NoError(s.Create(&session.Session{SessionID: "legacy", UserID: 1, ValidUntil: validUntil}))
+	r.NoError(s.Create(&session.Session{SessionID: "first", UserID: 2, Family: "first", ValidUntil: validUntil}))
+
+	r.Equal(session.ErrNoFamily, s.DeleteFamily(""))
+
+	_, err := s.FindByToken("legacy")
+	r.NoError(err)
+
+	r.NoError(s.DeleteFamily("first"))
+
+	_, err = s.FindByToken("first")
+	r.Error(err)
+}
+
+// nolint: gomnd
+func Test_UserStorageConcurrent(t *testing.T) {
+	r := require.New(t)
+	s := NewUserStorage()
+
+	u := &user.User{Email: "go_dev@tinkoff.ru"}
+	r.NoError(s.Create(u))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			token := fmt.Sprintf("token%d", i)
+			_ = s.CreateToken(&user.Token{Token: token, UserID: u.ID, Purpose: user.VerifyEmail, ValidUntil: time.Now().Add(time.Minute)})
+			_, _ = s.UseToken(token, user.VerifyEmail)
+			_ = s.SetEmailVerified(u.ID)
+			_ = s.UpdatePassword(u.ID, token)
+			_, _ = s.FindByID(u.ID)
+		}(i)
+	}
+
+	wg.Wait()
+
+	stored, err := s.FindByID(u.ID)
+	r.NoError(err)
+	r.True(stored.EmailVerified)
+}
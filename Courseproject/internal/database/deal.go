@@ -0,0 +1,50 @@
+package database
+
+import (
+	"../deal"
+)
+
+var _ deal.Storage = &DealStorage{}
+
+// DealStorage reads deals from the fills and the orders of the order storage
+type DealStorage struct {
+	orders *OrderStorage
+}
+
+func NewDealStorage(orders *OrderStorage) *DealStorage {
+	return &DealStorage{orders: orders}
+}
+
+func (s *DealStorage) GetByRobotID(robotID int64, limit, offset int) ([]*deal.Deal, error) {
+	s.orders.mutex.RLock()
+	defer s.orders.mutex.RUnlock()
+
+	var deals []*deal.Deal
+
+	for i := len(s.orders.fills) - 1; i >= 0 && len(deals) < limit; i-- {
+		f := s.orders.fills[i]
+		if f.RobotID != robotID {
+			continue
+		}
+
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		deals = append(deals, &deal.Deal{
+			DealID:     f.FillID,
+			RobotID:    f.RobotID,
+			OrderID:    f.OrderID,
+			Ticker:     f.Ticker,
+			Side:       f.Side,
+			Price:      f.Price,
+			Quantity:   f.Quantity,
+			Fee:        f.Fee,
+			QuoteTime:  s.orders.orders[f.OrderID].QuoteTime,
+			ExecutedAt: f.FilledAt,
+		})
+	}
+
+	return deals, nil
+}
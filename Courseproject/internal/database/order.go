@@ -0,0 +1,107 @@
+package database
+
+import (
+	"sync"
+
+	"../order"
+)
+
+var _ order.Storage = &OrderStorage{}
+
+type OrderStorage struct {
+	orders map[string]order.Order
+	fills  []order.Fill
+	mutex  sync.RWMutex
+}
+
+func NewOrderStorage() *OrderStorage {
+	return &OrderStorage{orders: make(map[string]order.Order)}
+}
+
+func (s *OrderStorage) Create(o *order.Order) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.orders[o.OrderID] = *o
+
+	return nil
+}
+
+func (s *OrderStorage) UpdateByID(o *order.Order) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.orders[o.OrderID]; !ok {
+		return order.ErrNotFound
+	}
+
+	s.orders[o.OrderID] = *o
+
+	return nil
+}
+
+func (s *OrderStorage) UpdateStatusByID(o *order.Order) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored, ok := s.orders[o.OrderID]
+	if !ok {
+		return order.ErrNotFound
+	}
+
+	stored.Status, stored.RejectReason, stored.UpdatedAt = o.Status, o.RejectReason, o.UpdatedAt
+	s.orders[o.OrderID] = stored
+
+	return nil
+}
+
+func (s *OrderStorage) FindByID(id string) (*order.Order, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	o, ok := s.orders[id]
+	if !ok {
+		return nil, order.ErrNotFound
+	}
+
+	return &o, nil
+}
+
+func (s *OrderStorage) FindActiveByRobotID(robotID int64) (*order.Order, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, o := range s.orders {
+		if o.RobotID == robotID && o.Active() {
+			return &o, nil
+		}
+	}
+
+	return nil, order.ErrNotFound
+}
+
+func (s *OrderStorage) CreateFill(f *order.Fill) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	f.FillID = int64(len(s.fills)) + 1
+	s.fills = append(s.fills, *f)
+
+	return nil
+}
+
+func (s *OrderStorage) GetFillsByOrderID(id string) ([]*order.Fill, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var fills []*order.Fill
+
+	for i := range s.fills {
+		if s.fills[i].OrderID == id {
+			f := s.fills[i]
+			fills = append(fills, &f)
+		}
+	}
+
+	return fills, nil
+}
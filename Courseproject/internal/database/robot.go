@@ -1,6 +1,7 @@
 package database
 
 import (
+	"sync"
 	"time"
 
 	"../robot"
@@ -11,7 +12,9 @@ var _ robot.Storage = &RobotStorage{}
 
 type RobotStorage struct {
 	robotDataID map[int64]*robot.Robot
+	positions   map[int64]robot.Position
 	size        int64
+	mutex       sync.RWMutex
 }
 
 var errActivation = errors.New("activation not available now")
@@ -20,33 +23,58 @@ var errDeactivation = errors.New("deactivation not available now")
 func NewRobotStorage() *RobotStorage {
 	s := &RobotStorage{}
 	s.robotDataID = make(map[int64]*robot.Robot)
+	s.positions = make(map[int64]robot.Position)
 
 	return s
 }
 
+// clone keeps stored robots apart from the ones callers modify
+func clone(r *robot.Robot) *robot.Robot {
+	c := *r
+	return &c
+}
+
+// deleted robots stay in the storage to be restored, lists skip them
+func deleted(r *robot.Robot) bool {
+	return r.DeletedAt.Valid
+}
+
 func (s *RobotStorage) Create(r *robot.Robot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	s.size++
 	r.RobotID = s.size
-	s.robotDataID[s.size] = r
+	r.PlanYield = r.PlanDealYield()
+	s.robotDataID[s.size] = clone(r)
 
 	return nil
 }
 
 func (s *RobotStorage) GetAllRobots() ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
+
 	for _, r := range s.robotDataID {
-		robotList = append(robotList, r)
+		if !deleted(r) {
+			robotList = append(robotList, clone(r))
+		}
 	}
 
 	return robotList, nil
 }
 
 func (s *RobotStorage) GetAllRobotsByOwnerID(id int64) ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
 
 	for _, r := range s.robotDataID {
-		if r.OwnerUserID == id {
-			robotList = append(robotList, r)
+		if !deleted(r) && r.OwnerUserID == id {
+			robotList = append(robotList, clone(r))
 		}
 	}
 
@@ -54,11 +82,14 @@ func (s *RobotStorage) GetAllRobotsByOwnerID(id int64) ([]*robot.Robot, error) {
 }
 
 func (s *RobotStorage) GetAllRobotsByTicker(ticker string) ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
 
 	for _, r := range s.robotDataID {
-		if r.Ticker == ticker {
-			robotList = append(robotList, r)
+		if !deleted(r) && r.Ticker == ticker {
+			robotList = append(robotList, clone(r))
 		}
 	}
 
@@ -66,11 +97,14 @@ func (s *RobotStorage) GetAllRobotsByTicker(ticker string) ([]*robot.Robot, erro
 }
 
 func (s *RobotStorage) GetAllRobotsByOwnerIDAndTicker(id int64, ticker string) ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
 
 	for _, r := range s.robotDataID {
-		if r.OwnerUserID == id && r.Ticker == ticker {
-			robotList = append(robotList, r)
+		if !deleted(r) && r.OwnerUserID == id && r.Ticker == ticker {
+			robotList = append(robotList, clone(r))
 		}
 	}
 
@@ -78,15 +112,21 @@ func (s *RobotStorage) GetAllRobotsByOwnerIDAndTicker(id int64, ticker string) (
 }
 
 func (s *RobotStorage) FindByID(id int64) (*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	r, ok := s.robotDataID[id]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	return r, nil
+	return clone(r), nil
 }
 
 func (s *RobotStorage) ActivateByID(id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	r, ok := s.robotDataID[id]
 	if !ok {
 		return errNotFound
@@ -98,18 +138,24 @@ func (s *RobotStorage) ActivateByID(id int64) error {
 
 	r.IsActive = true
 	r.ActivatedAt = time.Now()
+	r.StopReason = ""
+	r.PeakYield = r.FactYield
 	s.robotDataID[id] = r
 
 	return nil
 }
 
 func (s *RobotStorage) DeactivateByID(id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	r, ok := s.robotDataID[id]
 	if !ok {
 		return errNotFound
 	}
 
-	if !r.IsActive || (r.PlanStart.Before(time.Now()) && r.PlanEnd.After(time.Now())) {
+	// the engine deactivates robots breaking their risk limits during the plan
+	if !r.IsActive {
 		return errDeactivation
 	}
 
@@ -121,33 +167,58 @@ func (s *RobotStorage) DeactivateByID(id int64) error {
 }
 
 func (s *RobotStorage) DeleteByID(id int64) error {
-	_, ok := s.robotDataID[id]
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[id]
 	if !ok {
 		return errNotFound
 	}
 
-	delete(s.robotDataID, id)
+	r.DeletedAt.Time = time.Now()
+	r.DeletedAt.Valid = true
+
+	return nil
+}
+
+func (s *RobotStorage) RestoreByID(id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	r.DeletedAt.Valid = false
 
 	return nil
 }
 
 func (s *RobotStorage) UpdateByID(r *robot.Robot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	_, ok := s.robotDataID[r.RobotID]
 	if !ok {
 		return errNotFound
 	}
 
-	s.robotDataID[r.RobotID] = r
+	r.PlanYield = r.PlanDealYield()
+	s.robotDataID[r.RobotID] = clone(r)
 
 	return nil
 }
 
 func (s *RobotStorage) GetRobotsNeedToRun() ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
 
 	for _, r := range s.robotDataID {
-		if r.IsActive && r.PlanStart.Before(time.Now()) && r.PlanEnd.After(time.Now()) {
-			robotList = append(robotList, r)
+		if !deleted(r) && r.IsActive && r.PlanStart.Before(time.Now()) && r.PlanEnd.After(time.Now()) {
+			robotList = append(robotList, clone(r))
 		}
 	}
 
@@ -159,7 +230,106 @@ func (s *RobotStorage) ActivateAllRobots() error {
 }
 
 func (s *RobotStorage) GetWorkingRobotsByTicker(ticker string) ([]*robot.Robot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	var robotList []*robot.Robot
 
+	for _, r := range s.robotDataID {
+		if !deleted(r) && r.Ticker == ticker && r.IsActive && r.PlanStart.Before(time.Now()) && r.PlanEnd.After(time.Now()) {
+			robotList = append(robotList, clone(r))
+		}
+	}
+
 	return robotList, nil
 }
+
+func (s *RobotStorage) FindPositionByID(id int64) (*robot.Position, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, ok := s.robotDataID[id]; !ok {
+		return nil, errNotFound
+	}
+
+	p := s.positions[id]
+
+	return &p, nil
+}
+
+func (s *RobotStorage) UpdatePositionByID(id int64, p *robot.Position) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.robotDataID[id]; !ok {
+		return errNotFound
+	}
+
+	s.positions[id] = *p
+
+	return nil
+}
+
+func (s *RobotStorage) UpdatePnLByID(pnl *robot.Robot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[pnl.RobotID]
+	if !ok {
+		return errNotFound
+	}
+
+	r.RealizedPnL, r.UnrealizedPnL = pnl.RealizedPnL, pnl.UnrealizedPnL
+	r.Fees, r.DealsCount = pnl.Fees, pnl.DealsCount
+	r.FactYield, r.PeakYield = pnl.FactYield, pnl.PeakYield
+
+	return nil
+}
+
+func (s *RobotStorage) UpdateUnrealizedPnLByID(id int64, pnl float64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	r.UnrealizedPnL = pnl
+	r.FactYield = r.RealizedPnL + pnl
+
+	if r.FactYield > r.PeakYield {
+		r.PeakYield = r.FactYield
+	}
+
+	return nil
+}
+
+func (s *RobotStorage) UpdateStopReasonByID(id int64, reason string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	r.StopReason = reason
+
+	return nil
+}
+
+func (s *RobotStorage) UpdateStatusByID(id int64, status string, failedReconnects int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	r, ok := s.robotDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	r.Status = status
+	r.FailedReconnects = failedReconnects
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package database
 
 import (
+	"sort"
+	"sync"
 	"time"
 
 	"../session"
@@ -9,53 +11,121 @@ import (
 var _ session.Storage = &SessionStorage{}
 
 type SessionStorage struct {
-	sessionDataID    map[int64]*session.Session
 	sessionDataToken map[string]*session.Session
+	mutex            sync.RWMutex
 }
 
 func NewSessionStorage() *SessionStorage {
 	s := &SessionStorage{}
-	s.sessionDataID = make(map[int64]*session.Session)
 	s.sessionDataToken = make(map[string]*session.Session)
 
 	return s
 }
 
 func (s *SessionStorage) Create(sess *session.Session) error {
-	sess.CreatedAt = time.Now()
-	sess.ValidUntil = sess.CreatedAt.Add(time.Minute * 30) // nolint: gomnd
-	s.sessionDataID[sess.UserID] = sess
-	s.sessionDataToken[sess.SessionID] = sess
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.add(sess)
 
 	return nil
 }
 
-func (s *SessionStorage) FindByID(id int64) (*session.Session, error) {
-	sess, ok := s.sessionDataID[id]
+// add stores the copy of the session, s.mutex must be held
+func (s *SessionStorage) add(sess *session.Session) {
+	sess.CreatedAt = time.Now()
+	stored := *sess
+	s.sessionDataToken[sess.SessionID] = &stored
+}
+
+func (s *SessionStorage) FindByToken(token string) (*session.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	sess, ok := s.sessionDataToken[token]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	return sess, nil
+	result := *sess
+
+	return &result, nil
 }
 
-func (s *SessionStorage) FindByToken(token string) (*session.Session, error) {
+func (s *SessionStorage) FindByUserID(userID int64) ([]*session.Session, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+
+	var result []*session.Session
+
+	for _, sess := range s.sessionDataToken {
+		if sess.UserID == userID && !sess.Rotated && now.Before(sess.ValidUntil) {
+			c := *sess
+			result = append(result, &c)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+func (s *SessionStorage) Rotate(token string, next *session.Session) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	sess, ok := s.sessionDataToken[token]
 	if !ok {
-		return nil, errNotFound
+		return errNotFound
+	}
+
+	if sess.Rotated {
+		return session.ErrTokenReused
 	}
 
-	return sess, nil
+	sess.Rotated = true
+	s.add(next)
+
+	return nil
 }
 
-func (s *SessionStorage) DeleteByID(id int64) error {
-	sess, ok := s.sessionDataID[id]
-	if !ok {
-		return nil
+func (s *SessionStorage) DeleteFamily(family string) error {
+	if family == "" {
+		return session.ErrNoFamily
 	}
 
-	delete(s.sessionDataToken, sess.SessionID)
-	delete(s.sessionDataID, id)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for token, sess := range s.sessionDataToken {
+		if sess.Family == family {
+			delete(s.sessionDataToken, token)
+		}
+	}
 
 	return nil
 }
+
+func (s *SessionStorage) DeleteExpired(before time.Time, limit int) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var n int64
+
+	for token, sess := range s.sessionDataToken {
+		if n == int64(limit) {
+			break
+		}
+
+		if sess.ValidUntil.Before(before) {
+			delete(s.sessionDataToken, token)
+			n++
+		}
+	}
+
+	return n, nil
+}
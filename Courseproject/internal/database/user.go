@@ -1,16 +1,25 @@
 package database
 
 import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
 	"../user"
 	"github.com/pkg/errors"
 )
 
 var _ user.Storage = &UserStorage{}
 
+// UserStorage keeps users by id and email, both maps share the stored users,
+// methods return copies
 type UserStorage struct {
-	userDataID    map[int64]*user.User
+	userDataID       map[int64]*user.User
 	sessionDataEmail map[string]*user.User
-	size      int64
+	tokens           map[string]*user.Token
+	size             int64
+	mutex            sync.RWMutex
 }
 
 var errExists = errors.New("user already exists")
@@ -20,11 +29,15 @@ func NewUserStorage() *UserStorage {
 	s := &UserStorage{}
 	s.userDataID = make(map[int64]*user.User)
 	s.sessionDataEmail = make(map[string]*user.User)
+	s.tokens = make(map[string]*user.Token)
 
 	return s
 }
 
 func (s *UserStorage) Create(u *user.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	_, ok := s.sessionDataEmail[u.Email]
 	if ok {
 		return errExists
@@ -32,39 +45,178 @@ func (s *UserStorage) Create(u *user.User) error {
 
 	s.size++
 	u.ID = s.size
-	s.userDataID[u.ID] = u
-	s.sessionDataEmail[u.Email] = u
+	stored := *u
+	s.userDataID[u.ID] = &stored
+	s.sessionDataEmail[u.Email] = &stored
 
 	return nil
 }
 
 func (s *UserStorage) FindByEmail(email string) (*user.User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	u, ok := s.sessionDataEmail[email]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	return u, nil
+	result := *u
+
+	return &result, nil
 }
 
 func (s *UserStorage) FindByID(id int64) (*user.User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
 	u, ok := s.userDataID[id]
 	if !ok {
 		return nil, errNotFound
 	}
 
-	return u, nil
+	result := *u
+
+	return &result, nil
 }
 
 func (s *UserStorage) UpdateByID(u *user.User) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
 	userData, ok := s.userDataID[u.ID]
 	if !ok {
 		return errNotFound
 	}
 
 	delete(s.sessionDataEmail, userData.Email)
-	s.userDataID[u.ID] = u
-	s.sessionDataEmail[u.Email] = u
+	stored := *u
+	s.userDataID[u.ID] = &stored
+	s.sessionDataEmail[u.Email] = &stored
+
+	return nil
+}
+
+func (s *UserStorage) SetEmailVerified(id int64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	u, ok := s.userDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	u.EmailVerified = true
+
+	return nil
+}
+
+func (s *UserStorage) UpdatePassword(id int64, hash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	u, ok := s.userDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	u.Password = hash
+
+	return nil
+}
+
+func (s *UserStorage) CreateToken(t *user.Token) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for token, other := range s.tokens {
+		if other.UserID == t.UserID && other.Purpose == t.Purpose {
+			delete(s.tokens, token)
+		}
+	}
+
+	stored := *t
+	stored.Token = user.HashToken(t.Token)
+	s.tokens[stored.Token] = &stored
+
+	return nil
+}
+
+func (s *UserStorage) UseToken(token, purpose string) (*user.Token, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	hash := user.HashToken(token)
+
+	t, ok := s.tokens[hash]
+	if !ok || t.Purpose != purpose || !time.Now().Before(t.ValidUntil) {
+		return nil, user.ErrWrongToken
+	}
+
+	delete(s.tokens, hash)
+
+	result := *t
+	result.Token = token
+
+	return &result, nil
+}
+
+func (s *UserStorage) Search(query string, limit, offset int) ([]*user.User, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	query = strings.ToLower(query)
+
+	var found []*user.User
+
+	for _, u := range s.userDataID {
+		if strings.Contains(strings.ToLower(u.Email+" "+u.FirstName+" "+u.LastName), query) {
+			c := *u
+			found = append(found, &c)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].ID < found[j].ID
+	})
+
+	if offset >= len(found) {
+		return nil, nil
+	}
+
+	found = found[offset:]
+
+	if len(found) > limit {
+		found = found[:limit]
+	}
+
+	return found, nil
+}
+
+func (s *UserStorage) SetRole(id int64, role string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	u, ok := s.userDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	u.Role = role
+
+	return nil
+}
+
+func (s *UserStorage) SetLocked(id int64, locked bool) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	u, ok := s.userDataID[id]
+	if !ok {
+		return errNotFound
+	}
+
+	u.Locked = locked
 
 	return nil
 }
@@ -0,0 +1,25 @@
+package deal
+
+import (
+	"time"
+)
+
+// Deal is an execution of a robot order, the ledger of deals explains
+// how the robot got its yield. Deals are read from the fills of the orders
+type Deal struct {
+	DealID     int64     `json:"deal_id"`
+	RobotID    int64     `json:"robot_id"`
+	OrderID    string    `json:"order_id"`
+	Ticker     string    `json:"ticker"`
+	Side       string    `json:"side"`
+	Price      float64   `json:"price"`
+	Quantity   int64     `json:"quantity"`
+	Fee        float64   `json:"fee"`
+	QuoteTime  time.Time `json:"quote_time"`
+	ExecutedAt time.Time `json:"executed_at"`
+}
+
+type Storage interface {
+	// GetByRobotID returns deals of the robot starting from the newest one
+	GetByRobotID(robotID int64, limit, offset int) ([]*Deal, error)
+}
@@ -0,0 +1,245 @@
+package exchange
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ streamer.OrderServiceServer = &Exchange{}
+
+const defaultFillInterval = time.Second
+
+// Config of the exchange stub
+type Config struct {
+	// FillInterval is the time between fills of an order
+	FillInterval time.Duration
+	// MaxFill is the maximum quantity filled at once, 0 fills orders completely
+	MaxFill int64
+	// RejectRate is the share of valid orders rejected at random
+	RejectRate float64
+	Seed       int64
+}
+
+// Exchange is an in-process stub of fintech.OrderService, it fills every
+// accepted limit order at its price in parts of Config.MaxFill
+type Exchange struct {
+	logger *zap.SugaredLogger
+	cfg    Config
+
+	mutex   sync.Mutex
+	random  *rand.Rand
+	orders  map[string]*order
+	fills   []*streamer.Fill
+	updated chan struct{} // closed and replaced on every fill
+
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+type order struct {
+	req    *streamer.PlaceOrderRequest
+	filled int64
+	status streamer.OrderStatus
+}
+
+func New(logger *zap.SugaredLogger, cfg Config) *Exchange {
+	if cfg.FillInterval <= 0 {
+		cfg.FillInterval = defaultFillInterval
+	}
+
+	return &Exchange{
+		logger:  logger,
+		cfg:     cfg,
+		random:  rand.New(rand.NewSource(cfg.Seed)), // nolint: gosec
+		orders:  make(map[string]*order),
+		updated: make(chan struct{}),
+		quit:    make(chan struct{}),
+	}
+}
+
+func (e *Exchange) PlaceOrder(_ context.Context, req *streamer.PlaceOrderRequest) (*streamer.PlaceOrderResponse, error) {
+	if req.OrderId == "" {
+		return nil, status.Error(codes.InvalidArgument, "order id is required")
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if o, ok := e.orders[req.OrderId]; ok {
+		return &streamer.PlaceOrderResponse{Status: o.status}, nil
+	}
+
+	o := &order{req: req, status: streamer.OrderStatus_NEW}
+	e.orders[req.OrderId] = o
+
+	if reason := e.check(req); reason != "" {
+		o.status = streamer.OrderStatus_REJECTED
+		e.logger.Infof("order %s is rejected: %s", req.OrderId, reason)
+
+		return &streamer.PlaceOrderResponse{Status: o.status, RejectReason: reason}, nil
+	}
+
+	e.logger.Infof("order %s: %s %d %s at %v", req.OrderId, req.Side, req.Quantity, req.Ticker, req.Price)
+
+	go e.execute(o)
+
+	return &streamer.PlaceOrderResponse{Status: o.status}, nil
+}
+
+// check returns the reason to reject the order, e.mutex must be held
+func (e *Exchange) check(req *streamer.PlaceOrderRequest) string {
+	switch {
+	case req.Ticker == "":
+		return "ticker is required"
+	case req.Quantity <= 0:
+		return "quantity must be positive"
+	case req.Price <= 0:
+		return "price must be positive"
+	case e.random.Float64() < e.cfg.RejectRate:
+		return "rejected by exchange"
+	}
+
+	return ""
+}
+
+func (e *Exchange) CancelOrder(_ context.Context, req *streamer.CancelOrderRequest) (*streamer.CancelOrderResponse, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	o, ok := e.orders[req.OrderId]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "order %q not found", req.OrderId)
+	}
+
+	if active(o.status) {
+		o.status = streamer.OrderStatus_CANCELLED
+		e.logger.Infof("order %s is cancelled", req.OrderId)
+	}
+
+	return &streamer.CancelOrderResponse{Status: o.status, FilledQuantity: o.filled}, nil
+}
+
+func (e *Exchange) Fills(req *streamer.FillsRequest, stream streamer.OrderService_FillsServer) error {
+	var since time.Time
+
+	if req.Since != nil {
+		ts, err := ptypes.Timestamp(req.Since)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "bad since: %v", err)
+		}
+
+		since = ts
+	}
+
+	e.mutex.Lock()
+	next := len(e.fills)
+
+	for i, f := range e.fills {
+		ts, _ := ptypes.Timestamp(f.Ts)
+		if !ts.Before(since) {
+			next = i
+			break
+		}
+	}
+	e.mutex.Unlock()
+
+	for {
+		e.mutex.Lock()
+		fills, updated := e.fills[next:], e.updated
+		e.mutex.Unlock()
+
+		for _, f := range fills {
+			if err := stream.Send(f); err != nil {
+				return errors.Wrap(err, "can't send fill")
+			}
+		}
+
+		next += len(fills)
+
+		select {
+		case <-updated:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-e.quit:
+			return status.Error(codes.Unavailable, "exchange is closed")
+		}
+	}
+}
+
+// execute fills the order in parts until it is done or cancelled
+func (e *Exchange) execute(o *order) {
+	ticker := time.NewTicker(e.cfg.FillInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.quit:
+			return
+		}
+
+		if !e.fill(o) {
+			return
+		}
+	}
+}
+
+// fill executes the next part of the order and reports whether it is still active
+func (e *Exchange) fill(o *order) bool {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if !active(o.status) {
+		return false
+	}
+
+	quantity := o.req.Quantity - o.filled
+	if e.cfg.MaxFill > 0 && quantity > e.cfg.MaxFill {
+		quantity = e.cfg.MaxFill
+	}
+
+	o.filled += quantity
+	o.status = streamer.OrderStatus_PARTIALLY_FILLED
+
+	if o.filled == o.req.Quantity {
+		o.status = streamer.OrderStatus_FILLED
+	}
+
+	e.fills = append(e.fills, &streamer.Fill{
+		OrderId:        o.req.OrderId,
+		Ticker:         o.req.Ticker,
+		Side:           o.req.Side,
+		Quantity:       quantity,
+		Price:          o.req.Price,
+		Ts:             ptypes.TimestampNow(),
+		Status:         o.status,
+		FilledQuantity: o.filled,
+	})
+
+	close(e.updated)
+	e.updated = make(chan struct{})
+
+	return active(o.status)
+}
+
+// Close stops filling orders and breaks fill streams
+func (e *Exchange) Close() error {
+	e.quitOnce.Do(func() {
+		close(e.quit)
+	})
+
+	return nil
+}
+
+func active(s streamer.OrderStatus) bool {
+	return s == streamer.OrderStatus_NEW || s == streamer.OrderStatus_PARTIALLY_FILLED
+}
@@ -0,0 +1,120 @@
+package exchange
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	streamer "../streamer"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nolint: gomnd
+func newTestClient(t *testing.T, cfg Config) streamer.OrderServiceClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	e := New(zap.NewNop().Sugar(), cfg)
+	srv := grpc.NewServer()
+	streamer.RegisterOrderServiceServer(srv, e)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		_ = e.Close()
+		srv.Stop()
+	})
+
+	return streamer.NewOrderServiceClient(conn)
+}
+
+// nolint: gomnd
+func Test_PartialFills(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	client := newTestClient(t, Config{FillInterval: time.Millisecond, MaxFill: 2})
+
+	resp, err := client.PlaceOrder(ctx, &streamer.PlaceOrderRequest{OrderId: "1", Ticker: "SBER", Side: streamer.OrderSide_BUY, Quantity: 5, Price: 100})
+	r.NoError(err)
+	r.Equal(streamer.OrderStatus_NEW, resp.Status)
+
+	fills, err := client.Fills(ctx, &streamer.FillsRequest{})
+	r.NoError(err)
+
+	expected := []struct {
+		quantity, filled int64
+		status           streamer.OrderStatus
+	}{
+		{2, 2, streamer.OrderStatus_PARTIALLY_FILLED},
+		{2, 4, streamer.OrderStatus_PARTIALLY_FILLED},
+		{1, 5, streamer.OrderStatus_FILLED},
+	}
+
+	for _, e := range expected {
+		f, err := fills.Recv()
+		r.NoError(err)
+		r.Equal("1", f.OrderId)
+		r.Equal(100.0, f.Price)
+		r.Equal(e.quantity, f.Quantity)
+		r.Equal(e.filled, f.FilledQuantity)
+		r.Equal(e.status, f.Status)
+	}
+
+	// fills are replayed to new streams
+	replay, err := client.Fills(ctx, &streamer.FillsRequest{})
+	r.NoError(err)
+
+	f, err := replay.Recv()
+	r.NoError(err)
+	r.Equal(int64(2), f.FilledQuantity)
+}
+
+// nolint: gomnd
+func Test_Reject(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	client := newTestClient(t, Config{FillInterval: time.Millisecond})
+
+	resp, err := client.PlaceOrder(ctx, &streamer.PlaceOrderRequest{OrderId: "1", Ticker: "SBER", Quantity: 0, Price: 100})
+	r.NoError(err)
+	r.Equal(streamer.OrderStatus_REJECTED, resp.Status)
+	r.NotEmpty(resp.RejectReason)
+
+	client = newTestClient(t, Config{FillInterval: time.Millisecond, RejectRate: 1})
+
+	resp, err = client.PlaceOrder(ctx, &streamer.PlaceOrderRequest{OrderId: "1", Ticker: "SBER", Quantity: 1, Price: 100})
+	r.NoError(err)
+	r.Equal(streamer.OrderStatus_REJECTED, resp.Status)
+
+	_, err = client.PlaceOrder(ctx, &streamer.PlaceOrderRequest{Ticker: "SBER", Quantity: 1, Price: 100})
+	r.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+// nolint: gomnd
+func Test_Cancel(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	client := newTestClient(t, Config{FillInterval: time.Hour})
+
+	_, err := client.PlaceOrder(ctx, &streamer.PlaceOrderRequest{OrderId: "1", Ticker: "SBER", Quantity: 1, Price: 100})
+	r.NoError(err)
+
+	resp, err := client.CancelOrder(ctx, &streamer.CancelOrderRequest{OrderId: "1"})
+	r.NoError(err)
+	r.Equal(streamer.OrderStatus_CANCELLED, resp.Status)
+	r.Zero(resp.FilledQuantity)
+
+	_, err = client.CancelOrder(ctx, &streamer.CancelOrderRequest{OrderId: "2"})
+	r.Equal(codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,135 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages to users
+type Mailer interface {
+	Send(m Message) error
+}
+
+// Config chooses SMTP if its address is set and the outbox otherwise
+type Config struct {
+	SMTPAddr  string
+	Username  string
+	Password  string
+	From      string
+	OutboxDir string
+}
+
+func New(cfg Config) (Mailer, error) {
+	if cfg.SMTPAddr == "" {
+		return NewOutbox(cfg.OutboxDir)
+	}
+
+	return NewSMTP(cfg)
+}
+
+// SMTP sends messages through the SMTP server, PLAIN auth is used if the username is set
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTP(cfg Config) (*SMTP, error) {
+	host, _, err := net.SplitHostPort(cfg.SMTPAddr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "wrong smtp address %q", cfg.SMTPAddr)
+	}
+
+	if cfg.From == "" {
+		return nil, errors.New("sender address is required")
+	}
+
+	s := &SMTP{addr: cfg.SMTPAddr, from: cfg.From}
+
+	if cfg.Username != "" {
+		s.auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+
+	return s, nil
+}
+
+func (s *SMTP) Send(m Message) error {
+	if err := smtp.SendMail(s.addr, s.auth, s.from, []string{m.To}, format(s.from, m)); err != nil {
+		return errors.Wrapf(err, "can't send mail to %s", m.To)
+	}
+
+	return nil
+}
+
+// format builds the plain text message with its headers
+func format(from string, m Message) []byte {
+	var b bytes.Buffer
+
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+
+	return b.Bytes()
+}
+
+// Outbox keeps messages for local runs and tests instead of sending them,
+// they are written to the directory as .eml files if it is set
+type Outbox struct {
+	dir      string
+	messages []Message
+	mutex    sync.Mutex
+}
+
+func NewOutbox(dir string) (*Outbox, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil { // nolint: gomnd
+			return nil, errors.Wrap(err, "can't create outbox")
+		}
+	}
+
+	return &Outbox{dir: dir}, nil
+}
+
+func (o *Outbox) Send(m Message) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	if o.dir != "" {
+		name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.NewReplacer("@", "_at_", "/", "_").Replace(m.To))
+
+		if err := ioutil.WriteFile(filepath.Join(o.dir, name), format("outbox", m), 0644); err != nil { // nolint: gomnd
+			return errors.Wrap(err, "can't write message")
+		}
+	}
+
+	o.messages = append(o.messages, m)
+
+	return nil
+}
+
+// Messages returns the messages sent so far
+func (o *Outbox) Messages() []Message {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	return append([]Message(nil), o.messages...)
+}
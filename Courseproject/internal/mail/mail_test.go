@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Outbox(t *testing.T) {
+	r := require.New(t)
+
+	dir, err := ioutil.TempDir("", "outbox")
+	r.NoError(err)
+
+	defer os.RemoveAll(dir)
+
+	mailer, err := New(Config{OutboxDir: filepath.Join(dir, "mail")})
+	r.NoError(err)
+
+	m := Message{To: "go_dev@tinkoff.ru", Subject: "Hello", Body: "line 1\nline 2"}
+	r.NoError(mailer.Send(m))
+
+	outbox, ok := mailer.(*Outbox)
+	r.True(ok)
+	r.Equal([]Message{m}, outbox.Messages())
+
+	files, err := filepath.Glob(filepath.Join(dir, "mail", "*go_dev_at_tinkoff.ru.eml"))
+	r.NoError(err)
+	r.Len(files, 1)
+
+	data, err := ioutil.ReadFile(files[0])
+	r.NoError(err)
+	r.Contains(string(data), "Subject: Hello\r\n")
+	r.Contains(string(data), "line 1\r\nline 2")
+
+	_, err = New(Config{SMTPAddr: "localhost"})
+	r.Error(err, "port is required")
+}
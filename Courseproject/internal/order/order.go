@@ -0,0 +1,83 @@
+package order
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Sides of an order
+const (
+	Buy  = "buy"
+	Sell = "sell"
+)
+
+// Statuses of an order on the exchange
+const (
+	StatusNew             = "new"
+	StatusPartiallyFilled = "partially_filled"
+	StatusFilled          = "filled"
+	StatusCancelled       = "cancelled"
+	StatusRejected        = "rejected"
+)
+
+var ErrNotFound = errors.New("order not found")
+
+// Order is a limit order sent by a robot to the exchange
+type Order struct {
+	OrderID        string    `json:"order_id"`
+	RobotID        int64     `json:"robot_id"`
+	Ticker         string    `json:"ticker"`
+	Side           string    `json:"side"`
+	Quantity       int64     `json:"quantity"`
+	Price          float64   `json:"price"`
+	FilledQuantity int64     `json:"filled_quantity"`
+	Status         string    `json:"status"`
+	RejectReason   string    `json:"reject_reason"`
+	QuoteTime      time.Time `json:"quote_time"` // time of the quote the robot decided on
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Active shows whether the exchange can still fill the order
+func (o *Order) Active() bool {
+	return o.Status == StatusNew || o.Status == StatusPartiallyFilled
+}
+
+// Fill is an execution of a part of the order
+type Fill struct {
+	FillID   int64     `json:"fill_id"`
+	OrderID  string    `json:"order_id"`
+	RobotID  int64     `json:"robot_id"`
+	Ticker   string    `json:"ticker"`
+	Side     string    `json:"side"`
+	Quantity int64     `json:"quantity"`
+	Price    float64   `json:"price"`
+	FilledAt time.Time `json:"filled_at"`
+	// Fee is the commission of the robot for the fill
+	Fee float64 `json:"fee"`
+}
+
+// Cash is the money the fill brings to the robot net of its commission,
+// buys are negative
+func (f *Fill) Cash() float64 {
+	amount := f.Price * float64(f.Quantity)
+	if f.Side == Buy {
+		return -amount - f.Fee
+	}
+
+	return amount - f.Fee
+}
+
+type Storage interface {
+	Create(o *Order) error
+	UpdateByID(o *Order) error
+	// UpdateStatusByID stores the status, the reject reason and the update time
+	// of the order keeping the filled quantity stored by its fills
+	UpdateStatusByID(o *Order) error
+	FindByID(id string) (*Order, error)
+	// FindActiveByRobotID returns ErrNotFound if the robot has no active order
+	FindActiveByRobotID(robotID int64) (*Order, error)
+	CreateFill(f *Fill) error
+	GetFillsByOrderID(id string) ([]*Fill, error)
+}
@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"../account"
+	"github.com/pkg/errors"
+)
+
+var _ account.Storage = &AccountStorage{}
+
+type AccountStorage struct {
+	statementStorage
+
+	findByUserIDStmt      *sql.Stmt
+	depositStmt           *sql.Stmt
+	withdrawStmt          *sql.Stmt
+	reserveStmt           *sql.Stmt
+	releaseStmt           *sql.Stmt
+	findReservationStmt   *sql.Stmt
+	updateReservationStmt *sql.Stmt
+}
+
+func NewAccountStorage(db *DB) (*AccountStorage, error) {
+	s := &AccountStorage{statementStorage: newStatementsStorage(db)}
+
+	stmts := []stmt{
+		{Query: findAccountByUserIDQuery, Dst: &s.findByUserIDStmt},
+		{Query: depositQuery, Dst: &s.depositStmt},
+		{Query: withdrawQuery, Dst: &s.withdrawStmt},
+		{Query: reserveQuery, Dst: &s.reserveStmt},
+		{Query: releaseQuery, Dst: &s.releaseStmt},
+		{Query: findReservationQuery, Dst: &s.findReservationStmt},
+		{Query: updateReservationQuery, Dst: &s.updateReservationStmt},
+	}
+
+	if err := s.initStatements(stmts); err != nil {
+		return nil, errors.Wrap(err, "can't init statements")
+	}
+
+	return s, nil
+}
+
+const findAccountByUserIDQuery = "SELECT a.user_id, a.balance, " +
+	"COALESCE((SELECT SUM(r.amount) FROM robot_reservations r WHERE r.user_id = a.user_id), 0), a.updated_at " +
+	"FROM accounts a WHERE a.user_id=$1"
+
+func (s *AccountStorage) FindByUserID(userID int64) (*account.Account, error) {
+	a := account.Account{UserID: userID}
+
+	err := s.findByUserIDStmt.QueryRow(userID).Scan(&a.UserID, &a.Balance, &a.Reserved, &a.UpdatedAt)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.Wrap(err, "can't scan account")
+	}
+
+	return &a, nil
+}
+
+const depositQuery = "INSERT INTO accounts(user_id, balance) VALUES ($1, $2) " +
+	"ON CONFLICT (user_id) DO UPDATE SET (balance, updated_at) = (accounts.balance + EXCLUDED.balance, now())"
+
+func (s *AccountStorage) Deposit(userID int64, amount float64) error {
+	if _, err := s.depositStmt.Exec(userID, amount); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const withdrawQuery = "UPDATE accounts SET (balance, updated_at) = (balance - $1, now()) WHERE user_id=$2 AND balance >= $1"
+
+func (s *AccountStorage) Withdraw(userID int64, amount float64) error {
+	res, err := s.withdrawStmt.Exec(amount, userID)
+	if err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return checkFunds(res)
+}
+
+const reserveQuery = "WITH spent AS (UPDATE accounts SET (balance, updated_at) = (balance - $3, now()) " +
+	"WHERE user_id=$1 AND balance >= $3 RETURNING user_id) " +
+	"INSERT INTO robot_reservations(robot_id, user_id, amount) SELECT $2, user_id, $3 FROM spent " +
+	"ON CONFLICT (robot_id) DO UPDATE SET amount = robot_reservations.amount + EXCLUDED.amount"
+
+func (s *AccountStorage) Reserve(userID, robotID int64, amount float64) error {
+	res, err := s.reserveStmt.Exec(userID, robotID, amount)
+	if err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return checkFunds(res)
+}
+
+func checkFunds(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "can't get affected rows")
+	}
+
+	if n == 0 {
+		return account.ErrInsufficientFunds
+	}
+
+	return nil
+}
+
+const releaseQuery = "WITH released AS (DELETE FROM robot_reservations WHERE robot_id=$1 RETURNING user_id, amount) " +
+	"UPDATE accounts a SET (balance, updated_at) = (a.balance + r.amount, now()) FROM released r WHERE a.user_id = r.user_id"
+
+func (s *AccountStorage) Release(robotID int64) error {
+	if _, err := s.releaseStmt.Exec(robotID); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const findReservationQuery = "SELECT amount FROM robot_reservations WHERE robot_id=$1 FOR UPDATE"
+
+const updateReservationQuery = "UPDATE robot_reservations SET amount = $1 WHERE robot_id=$2"
+
+func (s *AccountStorage) Settle(userID, robotID int64, cash float64) error {
+	tx, err := s.db.Session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "can't begin transaction")
+	}
+
+	defer tx.Rollback() // nolint: errcheck
+
+	var reserved float64
+
+	err = tx.Stmt(s.findReservationStmt).QueryRow(robotID).Scan(&reserved)
+
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return errors.Wrap(err, "can't scan reservation")
+	default:
+		reserved += cash
+		cash = 0
+
+		if reserved < 0 {
+			reserved, cash = 0, reserved
+		}
+
+		if _, err := tx.Stmt(s.updateReservationStmt).Exec(reserved, robotID); err != nil {
+			return errors.Wrap(err, "can't update reservation")
+		}
+	}
+
+	if cash != 0 {
+		if _, err := tx.Stmt(s.depositStmt).Exec(userID, cash); err != nil {
+			return errors.Wrap(err, "can't update balance")
+		}
+	}
+
+	return errors.Wrap(tx.Commit(), "can't commit transaction")
+}
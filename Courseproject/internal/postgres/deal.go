@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"../deal"
+	"github.com/pkg/errors"
+)
+
+var _ deal.Storage = &DealStorage{}
+
+type DealStorage struct {
+	statementStorage
+
+	getByRobotIDStmt *sql.Stmt
+}
+
+func NewDealStorage(db *DB) (*DealStorage, error) {
+	s := &DealStorage{statementStorage: newStatementsStorage(db)}
+
+	stmts := []stmt{
+		{Query: getDealsByRobotIDQuery, Dst: &s.getByRobotIDStmt},
+	}
+
+	if err := s.initStatements(stmts); err != nil {
+		return nil, errors.Wrap(err, "can't init statements")
+	}
+
+	return s, nil
+}
+
+// deals are the fills of the robot orders
+const getDealsByRobotIDQuery = "SELECT f.fill_id, f.robot_id, f.order_id, f.ticker, f.side, f.price, f.quantity, f.fee, o.quote_time, f.filled_at " +
+	"FROM fills f JOIN orders o ON o.order_id=f.order_id WHERE f.robot_id=$1 ORDER BY f.fill_id DESC LIMIT $2 OFFSET $3"
+
+func (s *DealStorage) GetByRobotID(robotID int64, limit, offset int) ([]*deal.Deal, error) {
+	rows, err := s.getByRobotIDStmt.Query(robotID, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't exec query")
+	}
+
+	defer rows.Close()
+
+	var deals []*deal.Deal
+
+	for rows.Next() {
+		d := new(deal.Deal)
+
+		if err := rows.Scan(&d.DealID, &d.RobotID, &d.OrderID, &d.Ticker, &d.Side, &d.Price, &d.Quantity, &d.Fee, &d.QuoteTime, &d.ExecutedAt); err != nil {
+			return nil, errors.Wrap(err, "can't scan deal")
+		}
+
+		deals = append(deals, d)
+	}
+
+	return deals, errors.Wrap(rows.Err(), "can't read deals")
+}
@@ -0,0 +1,151 @@
+package postgres
+
+import (
+	"database/sql"
+
+	"../order"
+	"github.com/pkg/errors"
+)
+
+var _ order.Storage = &OrderStorage{}
+
+type OrderStorage struct {
+	statementStorage
+
+	createStmt              *sql.Stmt
+	updateByIDStmt          *sql.Stmt
+	updateStatusByIDStmt    *sql.Stmt
+	findByIDStmt            *sql.Stmt
+	findActiveByRobotIDStmt *sql.Stmt
+	createFillStmt          *sql.Stmt
+	getFillsByOrderIDStmt   *sql.Stmt
+}
+
+func NewOrderStorage(db *DB) (*OrderStorage, error) {
+	s := &OrderStorage{statementStorage: newStatementsStorage(db)}
+
+	stmts := []stmt{
+		{Query: createOrderQuery, Dst: &s.createStmt},
+		{Query: updateOrderByIDQuery, Dst: &s.updateByIDStmt},
+		{Query: updateOrderStatusByIDQuery, Dst: &s.updateStatusByIDStmt},
+		{Query: findOrderByIDQuery, Dst: &s.findByIDStmt},
+		{Query: findActiveOrderByRobotIDQuery, Dst: &s.findActiveByRobotIDStmt},
+		{Query: createFillQuery, Dst: &s.createFillStmt},
+		{Query: getFillsByOrderIDQuery, Dst: &s.getFillsByOrderIDStmt},
+	}
+
+	if err := s.initStatements(stmts); err != nil {
+		return nil, errors.Wrap(err, "can't init statements")
+	}
+
+	return s, nil
+}
+
+const orderFields = "order_id, robot_id, ticker, side, quantity, price, filled_quantity, status, reject_reason, quote_time, created_at, updated_at"
+
+func scanOrder(scanner sqlScanner, o *order.Order) error {
+	return scanner.Scan(&o.OrderID, &o.RobotID, &o.Ticker, &o.Side, &o.Quantity, &o.Price, &o.FilledQuantity, &o.Status, &o.RejectReason, &o.QuoteTime, &o.CreatedAt, &o.UpdatedAt)
+}
+
+const createOrderQuery = "INSERT INTO orders(" + orderFields + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)"
+
+func (s *OrderStorage) Create(o *order.Order) error {
+	if _, err := s.createStmt.Exec(o.OrderID, o.RobotID, o.Ticker, o.Side, o.Quantity, o.Price, o.FilledQuantity, o.Status, o.RejectReason, o.QuoteTime, o.CreatedAt, o.UpdatedAt); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updateOrderByIDQuery = "UPDATE orders SET (filled_quantity, status, reject_reason, updated_at) = ($1, $2, $3, $4) WHERE order_id=$5"
+
+func (s *OrderStorage) UpdateByID(o *order.Order) error {
+	if _, err := s.updateByIDStmt.Exec(o.FilledQuantity, o.Status, o.RejectReason, o.UpdatedAt, o.OrderID); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updateOrderStatusByIDQuery = "UPDATE orders SET (status, reject_reason, updated_at) = ($1, $2, $3) WHERE order_id=$4"
+
+func (s *OrderStorage) UpdateStatusByID(o *order.Order) error {
+	if _, err := s.updateStatusByIDStmt.Exec(o.Status, o.RejectReason, o.UpdatedAt, o.OrderID); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const findOrderByIDQuery = "SELECT " + orderFields + " FROM orders WHERE order_id=$1"
+
+func (s *OrderStorage) FindByID(id string) (*order.Order, error) {
+	var o order.Order
+
+	err := scanOrder(s.findByIDStmt.QueryRow(id), &o)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, order.ErrNotFound
+	case err != nil:
+		return nil, errors.Wrap(err, "can't scan order")
+	}
+
+	return &o, nil
+}
+
+const findActiveOrderByRobotIDQuery = "SELECT " + orderFields + " FROM orders " +
+	"WHERE robot_id=$1 AND status IN ('" + order.StatusNew + "', '" + order.StatusPartiallyFilled + "') " +
+	"ORDER BY created_at DESC LIMIT 1"
+
+func (s *OrderStorage) FindActiveByRobotID(robotID int64) (*order.Order, error) {
+	var o order.Order
+
+	err := scanOrder(s.findActiveByRobotIDStmt.QueryRow(robotID), &o)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, order.ErrNotFound
+	case err != nil:
+		return nil, errors.Wrap(err, "can't scan order")
+	}
+
+	return &o, nil
+}
+
+const createFillQuery = "INSERT INTO fills(order_id, robot_id, ticker, side, quantity, price, fee, filled_at) " +
+	"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING fill_id"
+
+func (s *OrderStorage) CreateFill(f *order.Fill) error {
+	if err := s.createFillStmt.QueryRow(f.OrderID, f.RobotID, f.Ticker, f.Side, f.Quantity, f.Price, f.Fee, f.FilledAt).Scan(&f.FillID); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const getFillsByOrderIDQuery = "SELECT fill_id, order_id, robot_id, ticker, side, quantity, price, fee, filled_at FROM fills " +
+	"WHERE order_id=$1 ORDER BY fill_id"
+
+func (s *OrderStorage) GetFillsByOrderID(id string) ([]*order.Fill, error) {
+	rows, err := s.getFillsByOrderIDStmt.Query(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't exec query")
+	}
+
+	defer rows.Close()
+
+	var fills []*order.Fill
+
+	for rows.Next() {
+		f := new(order.Fill)
+
+		if err := rows.Scan(&f.FillID, &f.OrderID, &f.RobotID, &f.Ticker, &f.Side, &f.Quantity, &f.Price, &f.Fee, &f.FilledAt); err != nil {
+			return nil, errors.Wrap(err, "can't scan fill")
+		}
+
+		fills = append(fills, f)
+	}
+
+	return fills, errors.Wrap(rows.Err(), "can't read fills")
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"database/sql"
 
+	"../../pkg/null"
 	"../robot"
 	"github.com/pkg/errors"
 )
@@ -19,12 +20,19 @@ type RobotStorage struct {
 	getAllRobotsByTickerStmt           *sql.Stmt
 	findByIDStmt                       *sql.Stmt
 	deleteByIDStmt                     *sql.Stmt
+	restoreByIDStmt                    *sql.Stmt
 	updateByIDStmt                     *sql.Stmt
 	activateByIDStmt                   *sql.Stmt
 	deactivateByIDStmt                 *sql.Stmt
 	getRobotsNeedToActivateStmt        *sql.Stmt
 	activateAllRobotsStmt              *sql.Stmt
 	GetWorkingRobotsByTickerStmt       *sql.Stmt
+	findPositionByIDStmt               *sql.Stmt
+	updatePositionByIDStmt             *sql.Stmt
+	updateStatusByIDStmt               *sql.Stmt
+	updatePnLByIDStmt                  *sql.Stmt
+	updateUnrealizedPnLByIDStmt        *sql.Stmt
+	updateStopReasonByIDStmt           *sql.Stmt
 }
 
 func NewRobotStorage(db *DB) (*RobotStorage, error) {
@@ -38,12 +46,19 @@ func NewRobotStorage(db *DB) (*RobotStorage, error) {
 		{Query: findAllRobotsByTickerQuery, Dst: &s.getAllRobotsByTickerStmt},
 		{Query: findRobotByIDQuery, Dst: &s.findByIDStmt},
 		{Query: deleteRobotByIDQuery, Dst: &s.deleteByIDStmt},
+		{Query: restoreRobotByIDQuery, Dst: &s.restoreByIDStmt},
 		{Query: updateRobotByIDQuery, Dst: &s.updateByIDStmt},
 		{Query: activateRobotByIDQuery, Dst: &s.activateByIDStmt},
 		{Query: deactivateRobotByIDQuery, Dst: &s.deactivateByIDStmt},
 		{Query: getRobotsNeedToActivateQuery, Dst: &s.getRobotsNeedToActivateStmt},
 		{Query: activateAllRobotsQuery, Dst: &s.activateAllRobotsStmt},
 		{Query: GetWorkingRobotsByTickerQuery, Dst: &s.GetWorkingRobotsByTickerStmt},
+		{Query: findPositionByIDQuery, Dst: &s.findPositionByIDStmt},
+		{Query: updatePositionByIDQuery, Dst: &s.updatePositionByIDStmt},
+		{Query: updateStatusByIDQuery, Dst: &s.updateStatusByIDStmt},
+		{Query: updatePnLByIDQuery, Dst: &s.updatePnLByIDStmt},
+		{Query: updateUnrealizedPnLByIDQuery, Dst: &s.updateUnrealizedPnLByIDStmt},
+		{Query: updateStopReasonByIDQuery, Dst: &s.updateStopReasonByIDStmt},
 	}
 
 	if err := s.initStatements(stmts); err != nil {
@@ -54,23 +69,30 @@ func NewRobotStorage(db *DB) (*RobotStorage, error) {
 }
 
 const robotFields = "robot_id, owner_user_id, parent_robot_id, is_favorite, is_active, ticker, buy_price, " +
-	"sell_price, plan_start, plan_end, fact_yield, deals_count, activated_at, deactivated_at, created_at"
+	"sell_price, plan_start, plan_end, plan_yield, fact_yield, deals_count, activated_at, deactivated_at, created_at, deleted_at, " +
+	"strategy, strategy_params, status, failed_reconnects, realized_pnl, unrealized_pnl, lot_size, max_position, commission_type, commission, fees, " +
+	"stop_loss, take_profit, max_drawdown, peak_yield, stop_reason"
 
 func scanRobot(scanner sqlScanner, r *robot.Robot) error {
-	return scanner.Scan(&r.RobotID, &r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.IsActive, &r.Ticker, &r.BuyPrice, &r.SellPrice, &r.PlanStart, &r.PlanEnd, &r.PlanYield, &r.FactYield, &r.DealsCount, &r.ActivatedAt, &r.DeactivatedAt, &r.CreatedAt, &r.DeletedAt)
+	return scanner.Scan(&r.RobotID, &r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.IsActive, &r.Ticker, &r.BuyPrice, &r.SellPrice, &r.PlanStart, &r.PlanEnd, &r.PlanYield, &r.FactYield, &r.DealsCount, &r.ActivatedAt, &r.DeactivatedAt, &r.CreatedAt, &r.DeletedAt, &r.Strategy, &r.StrategyParams, &r.Status, &r.FailedReconnects, &r.RealizedPnL, &r.UnrealizedPnL, &r.LotSize, &r.MaxPosition, &r.CommissionType, &r.Commission, &r.Fees, &r.StopLoss, &r.TakeProfit, &r.MaxDrawdown, &r.PeakYield, &r.StopReason)
 }
 
-const createRobotQuery = "INSERT INTO robots(owner_user_id, parent_robot_id, is_favorite, ticker) VALUES ($1, $2, $3, $4) RETURNING robot_id"
+// the plan yield is derived from the prices and the plan stored with it
+const createRobotQuery = "INSERT INTO robots(owner_user_id, parent_robot_id, is_favorite, ticker, buy_price, sell_price, plan_start, plan_end, " +
+	"strategy, strategy_params, plan_yield, lot_size, max_position, commission_type, commission, stop_loss, take_profit, max_drawdown) " +
+	"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) RETURNING robot_id"
 
 func (s *RobotStorage) Create(r *robot.Robot) error {
-	if err := s.createStmt.QueryRow(&r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.Ticker).Scan(&r.RobotID); err != nil {
+	r.PlanYield = r.PlanDealYield()
+
+	if err := s.createStmt.QueryRow(&r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.Ticker, &r.BuyPrice, &r.SellPrice, &r.PlanStart, &r.PlanEnd, &r.Strategy, r.StrategyParams, &r.PlanYield, r.Lots(), &r.MaxPosition, &r.CommissionType, &r.Commission, &r.StopLoss, &r.TakeProfit, &r.MaxDrawdown).Scan(&r.RobotID); err != nil {
 		return errors.Wrap(err, "can't exec query")
 	}
 
 	return nil
 }
 
-const findAllRobotsQuery = "SELECT * FROM robots WHERE deleted_at IS NULL"
+const findAllRobotsQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL"
 
 func (s *RobotStorage) GetAllRobots() ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -93,7 +115,7 @@ func (s *RobotStorage) GetAllRobots() ([]*robot.Robot, error) {
 	return robots, nil
 }
 
-const findAllRobotsByOwnerIDAndTickerQuery = "SELECT * FROM robots WHERE deleted_at IS NULL AND owner_user_id=$1 AND ticker=$2"
+const findAllRobotsByOwnerIDAndTickerQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL AND owner_user_id=$1 AND ticker=$2"
 
 func (s *RobotStorage) GetAllRobotsByOwnerIDAndTicker(id int64, ticker string) ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -116,7 +138,7 @@ func (s *RobotStorage) GetAllRobotsByOwnerIDAndTicker(id int64, ticker string) (
 	return robots, nil
 }
 
-const findAllRobotsByOwnerIDQuery = "SELECT * FROM robots WHERE deleted_at IS NULL AND owner_user_id=$1"
+const findAllRobotsByOwnerIDQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL AND owner_user_id=$1"
 
 func (s *RobotStorage) GetAllRobotsByOwnerID(id int64) ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -139,7 +161,7 @@ func (s *RobotStorage) GetAllRobotsByOwnerID(id int64) ([]*robot.Robot, error) {
 	return robots, nil
 }
 
-const findAllRobotsByTickerQuery = "SELECT * FROM robots WHERE deleted_at IS NULL AND ticker=$1"
+const findAllRobotsByTickerQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL AND ticker=$1"
 
 func (s *RobotStorage) GetAllRobotsByTicker(ticker string) ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -162,7 +184,7 @@ func (s *RobotStorage) GetAllRobotsByTicker(ticker string) ([]*robot.Robot, erro
 	return robots, nil
 }
 
-const findRobotByIDQuery = "SELECT * FROM robots WHERE robot_id=$1"
+const findRobotByIDQuery = "SELECT " + robotFields + " FROM robots WHERE robot_id=$1"
 
 func (s *RobotStorage) FindByID(id int64) (*robot.Robot, error) {
 	var r robot.Robot
@@ -187,11 +209,26 @@ func (s *RobotStorage) DeleteByID(id int64) error {
 	return nil
 }
 
+const restoreRobotByIDQuery = "UPDATE robots SET deleted_at = NULL WHERE robot_id=$1"
+
+func (s *RobotStorage) RestoreByID(id int64) error {
+	if _, err := s.restoreByIDStmt.Exec(id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
 const updateRobotByIDQuery = "UPDATE robots SET (owner_user_id, parent_robot_id, is_favorite, is_active, ticker, buy_price, " +
-	"sell_price, plan_start, plan_end, plan_yield, fact_yield, deals_count, activated_at, deactivated_at, created_at, deleted_at) = ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) WHERE robot_id=$17"
+	"sell_price, plan_start, plan_end, plan_yield, fact_yield, deals_count, activated_at, deactivated_at, created_at, deleted_at, strategy, strategy_params, status, failed_reconnects, realized_pnl, unrealized_pnl, " +
+	"lot_size, max_position, commission_type, commission, fees, stop_loss, take_profit, max_drawdown, peak_yield, stop_reason) " +
+	"= ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, " +
+	"$28, $29, $30, $31, $32) WHERE robot_id=$33"
 
 func (s *RobotStorage) UpdateByID(r *robot.Robot) error {
-	_, err := s.updateByIDStmt.Exec(&r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.IsActive, &r.Ticker, &r.BuyPrice, &r.SellPrice, &r.PlanStart, &r.PlanEnd, &r.PlanYield, &r.FactYield, &r.DealsCount, &r.ActivatedAt, &r.DeactivatedAt, &r.CreatedAt, &r.DeletedAt, &r.RobotID)
+	r.PlanYield = r.PlanDealYield()
+
+	_, err := s.updateByIDStmt.Exec(&r.OwnerUserID, &r.ParentRobotID, &r.IsFavorite, &r.IsActive, &r.Ticker, &r.BuyPrice, &r.SellPrice, &r.PlanStart, &r.PlanEnd, &r.PlanYield, &r.FactYield, &r.DealsCount, &r.ActivatedAt, &r.DeactivatedAt, &r.CreatedAt, &r.DeletedAt, &r.Strategy, r.StrategyParams, &r.Status, &r.FailedReconnects, &r.RealizedPnL, &r.UnrealizedPnL, r.Lots(), &r.MaxPosition, &r.CommissionType, &r.Commission, &r.Fees, &r.StopLoss, &r.TakeProfit, &r.MaxDrawdown, &r.PeakYield, &r.StopReason, &r.RobotID)
 
 	if err != nil {
 		return errors.Wrap(err, "can't scan user")
@@ -200,7 +237,7 @@ func (s *RobotStorage) UpdateByID(r *robot.Robot) error {
 	return nil
 }
 
-const activateRobotByIDQuery = "UPDATE robots SET (activated_at, is_active) = (now(), true) WHERE robot_id=$1"
+const activateRobotByIDQuery = "UPDATE robots SET (activated_at, is_active, stop_reason, peak_yield) = (now(), true, '', fact_yield) WHERE robot_id=$1"
 
 func (s *RobotStorage) ActivateByID(id int64) error {
 	_, err := s.activateByIDStmt.Exec(id)
@@ -224,7 +261,7 @@ func (s *RobotStorage) DeactivateByID(id int64) error {
 	return nil
 }
 
-const getRobotsNeedToActivateQuery = "SELECT * FROM robots WHERE deleted_at IS NULL AND is_active=true AND plan_start < now() AND plan_end > now()"
+const getRobotsNeedToActivateQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL AND is_active=true AND plan_start < now() AND plan_end > now()"
 
 func (s *RobotStorage) GetRobotsNeedToRun() ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -258,7 +295,7 @@ func (s *RobotStorage) ActivateAllRobots() error {
 	return nil
 }
 
-const GetWorkingRobotsByTickerQuery = "SELECT * FROM robots WHERE deleted_at IS NULL AND ticker=$1 AND is_active=true AND activated_at < now() AND deactivated_at > now()"
+const GetWorkingRobotsByTickerQuery = "SELECT " + robotFields + " FROM robots WHERE deleted_at IS NULL AND ticker=$1 AND is_active=true AND plan_start < now() AND plan_end > now()"
 
 func (s *RobotStorage) GetWorkingRobotsByTicker(ticker string) ([]*robot.Robot, error) {
 	robots := make([]*robot.Robot, 0)
@@ -269,7 +306,7 @@ func (s *RobotStorage) GetWorkingRobotsByTicker(ticker string) ([]*robot.Robot,
 	}
 
 	for rows.Next() {
-		var r *robot.Robot
+		r := new(robot.Robot)
 
 		if err := scanRobot(rows, r); err != nil {
 			return nil, errors.Wrap(err, "can't scan robot")
@@ -280,3 +317,85 @@ func (s *RobotStorage) GetWorkingRobotsByTicker(ticker string) ([]*robot.Robot,
 
 	return robots, nil
 }
+
+const findPositionByIDQuery = "SELECT side, quantity, entry_price, entry_time FROM robot_positions WHERE robot_id=$1"
+
+func (s *RobotStorage) FindPositionByID(id int64) (*robot.Position, error) {
+	var (
+		p         robot.Position
+		entryTime null.NullTime
+	)
+
+	err := s.findPositionByIDStmt.QueryRow(id).Scan(&p.Side, &p.Quantity, &p.EntryPrice, &entryTime)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return &robot.Position{Side: robot.Sold}, nil
+	case err != nil:
+		return nil, errors.Wrap(err, "can't scan position")
+	}
+
+	p.EntryTime = entryTime.Time
+
+	return &p, nil
+}
+
+const updatePositionByIDQuery = "INSERT INTO robot_positions(robot_id, side, quantity, entry_price, entry_time) VALUES ($1, $2, $3, $4, $5) " +
+	"ON CONFLICT (robot_id) DO UPDATE SET (side, quantity, entry_price, entry_time, updated_at) = " +
+	"(EXCLUDED.side, EXCLUDED.quantity, EXCLUDED.entry_price, EXCLUDED.entry_time, now())"
+
+func (s *RobotStorage) UpdatePositionByID(id int64, p *robot.Position) error {
+	var entryTime null.NullTime
+
+	if !p.EntryTime.IsZero() {
+		entryTime.Time, entryTime.Valid = p.EntryTime, true
+	}
+
+	if _, err := s.updatePositionByIDStmt.Exec(id, p.Side, p.Quantity, p.EntryPrice, entryTime); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updateStatusByIDQuery = "UPDATE robots SET (status, failed_reconnects) = ($1, $2) WHERE robot_id=$3"
+
+func (s *RobotStorage) UpdateStatusByID(id int64, status string, failedReconnects int64) error {
+	if _, err := s.updateStatusByIDStmt.Exec(status, failedReconnects, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updatePnLByIDQuery = "UPDATE robots SET (realized_pnl, unrealized_pnl, fees, deals_count, fact_yield, peak_yield) = " +
+	"($1, $2, $3, $4, $5, $6) WHERE robot_id=$7"
+
+func (s *RobotStorage) UpdatePnLByID(r *robot.Robot) error {
+	if _, err := s.updatePnLByIDStmt.Exec(r.RealizedPnL, r.UnrealizedPnL, r.Fees, r.DealsCount, r.FactYield, r.PeakYield, r.RobotID); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updateUnrealizedPnLByIDQuery = "UPDATE robots SET (unrealized_pnl, fact_yield, peak_yield) = " +
+	"($1, realized_pnl + $1, GREATEST(peak_yield, realized_pnl + $1)) WHERE robot_id=$2"
+
+func (s *RobotStorage) UpdateUnrealizedPnLByID(id int64, pnl float64) error {
+	if _, err := s.updateUnrealizedPnLByIDStmt.Exec(pnl, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updateStopReasonByIDQuery = "UPDATE robots SET stop_reason = $1 WHERE robot_id=$2"
+
+func (s *RobotStorage) UpdateStopReasonByID(id int64, reason string) error {
+	if _, err := s.updateStopReasonByIDStmt.Exec(reason, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"database/sql"
+	"time"
 
 	"../session"
 	"github.com/pkg/errors"
@@ -12,10 +13,12 @@ var _ session.Storage = &SessionStorage{}
 type SessionStorage struct {
 	statementStorage
 
-	createStmt     *sql.Stmt
-	findByIDStmt   *sql.Stmt
+	createStmt        *sql.Stmt
 	findByTokenStmt   *sql.Stmt
-	deleteByIDStmt *sql.Stmt
+	findByUserIDStmt  *sql.Stmt
+	rotateStmt        *sql.Stmt
+	deleteFamilyStmt  *sql.Stmt
+	deleteExpiredStmt *sql.Stmt
 }
 
 func NewSessionStorage(db *DB) (*SessionStorage, error) {
@@ -23,9 +26,11 @@ func NewSessionStorage(db *DB) (*SessionStorage, error) {
 
 	stmts := []stmt{
 		{Query: createSessionQuery, Dst: &s.createStmt},
-		{Query: findSessionByIDQuery, Dst: &s.findByIDStmt},
 		{Query: findSessionByTokenQuery, Dst: &s.findByTokenStmt},
-		{Query: deleteSessionByIDQuery, Dst: &s.deleteByIDStmt},
+		{Query: findSessionsByUserIDQuery, Dst: &s.findByUserIDStmt},
+		{Query: rotateSessionQuery, Dst: &s.rotateStmt},
+		{Query: deleteSessionFamilyQuery, Dst: &s.deleteFamilyStmt},
+		{Query: deleteExpiredSessionsQuery, Dst: &s.deleteExpiredStmt},
 	}
 
 	if err := s.initStatements(stmts); err != nil {
@@ -35,28 +40,35 @@ func NewSessionStorage(db *DB) (*SessionStorage, error) {
 	return s, nil
 }
 
-const sessionFields = "session_id, user_id, created_at, valid_until"
+const sessionFields = "session_id, user_id, family, rotated, user_agent, ip, created_at, valid_until"
 
 func scanSession(scanner sqlScanner, sess *session.Session) error {
-	return scanner.Scan(&sess.SessionID, &sess.UserID, &sess.CreatedAt, &sess.ValidUntil)
+	return scanner.Scan(&sess.SessionID, &sess.UserID, &sess.Family, &sess.Rotated, &sess.UserAgent, &sess.IP,
+		&sess.CreatedAt, &sess.ValidUntil)
 }
 
-const createSessionQuery = "INSERT INTO session(session_id, user_id) VALUES ($1, $2) RETURNING valid_until"
+const createSessionQuery = "INSERT INTO session(session_id, user_id, family, user_agent, ip, valid_until) " +
+	"VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at"
 
 func (s *SessionStorage) Create(sess *session.Session) error {
-	if err := s.createStmt.QueryRow(&sess.SessionID, &sess.UserID).Scan(&sess.ValidUntil); err != nil {
+	return create(s.createStmt, sess)
+}
+
+func create(stmt *sql.Stmt, sess *session.Session) error {
+	err := stmt.QueryRow(sess.SessionID, sess.UserID, sess.Family, sess.UserAgent, sess.IP, sess.ValidUntil).Scan(&sess.CreatedAt)
+	if err != nil {
 		return errors.Wrap(err, "can't exec query")
 	}
 
 	return nil
 }
 
-const findSessionByIDQuery = "SELECT " + sessionFields + " FROM session WHERE user_id=$1"
+const findSessionByTokenQuery = "SELECT " + sessionFields + " FROM session WHERE session_id=$1"
 
-func (s *SessionStorage) FindByID(id int64) (*session.Session, error) {
+func (s *SessionStorage) FindByToken(token string) (*session.Session, error) {
 	var sess session.Session
 
-	row := s.findByIDStmt.QueryRow(id)
+	row := s.findByTokenStmt.QueryRow(token)
 
 	if err := scanSession(row, &sess); err != nil {
 		return nil, errors.Wrap(err, "can't scan session")
@@ -65,27 +77,90 @@ func (s *SessionStorage) FindByID(id int64) (*session.Session, error) {
 	return &sess, nil
 }
 
-const findSessionByTokenQuery = "SELECT " + sessionFields + " FROM session WHERE session_id=$1"
+const findSessionsByUserIDQuery = "SELECT " + sessionFields + " FROM session " +
+	"WHERE user_id=$1 AND NOT rotated AND valid_until > now() ORDER BY created_at"
 
-func (s *SessionStorage) FindByToken(token string) (*session.Session, error) {
-	var sess session.Session
+func (s *SessionStorage) FindByUserID(userID int64) ([]*session.Session, error) {
+	rows, err := s.findByUserIDStmt.Query(userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't exec query")
+	}
 
-	row := s.findByTokenStmt.QueryRow(token)
+	defer rows.Close()
 
-	if err := scanSession(row, &sess); err != nil {
-		return nil, errors.Wrap(err, "can't scan session")
+	var sessions []*session.Session
+
+	for rows.Next() {
+		sess := new(session.Session)
+
+		if err := scanSession(rows, sess); err != nil {
+			return nil, errors.Wrap(err, "can't scan session")
+		}
+
+		sessions = append(sessions, sess)
 	}
 
-	return &sess, nil
+	return sessions, errors.Wrap(rows.Err(), "can't read sessions")
 }
 
-const deleteSessionByIDQuery = "DELETE FROM session WHERE user_id=$1"
+const rotateSessionQuery = "UPDATE session SET rotated = true WHERE session_id=$1 AND NOT rotated"
 
-func (s *SessionStorage) DeleteByID(id int64) error {
-	_, err := s.deleteByIDStmt.Exec(id)
+func (s *SessionStorage) Rotate(token string, next *session.Session) error {
+	tx, err := s.db.Session.Begin()
 	if err != nil {
-		return errors.Wrap(err, "can't remove session")
+		return errors.Wrap(err, "can't begin transaction")
+	}
+
+	defer tx.Rollback() // nolint: errcheck
+
+	res, err := tx.Stmt(s.rotateStmt).Exec(token)
+	if err != nil {
+		return errors.Wrap(err, "can't rotate session")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "can't get affected rows")
+	}
+
+	if n == 0 {
+		return session.ErrTokenReused
+	}
+
+	if err = create(tx.Stmt(s.createStmt), next); err != nil {
+		return err
+	}
+
+	return errors.Wrap(tx.Commit(), "can't commit transaction")
+}
+
+const deleteSessionFamilyQuery = "DELETE FROM session WHERE family=$1"
+
+func (s *SessionStorage) DeleteFamily(family string) error {
+	if family == "" {
+		return session.ErrNoFamily
+	}
+
+	if _, err := s.deleteFamilyStmt.Exec(family); err != nil {
+		return errors.Wrap(err, "can't remove sessions")
 	}
 
 	return nil
 }
+
+const deleteExpiredSessionsQuery = "DELETE FROM session WHERE session_id IN " +
+	"(SELECT session_id FROM session WHERE valid_until < $1 LIMIT $2)"
+
+func (s *SessionStorage) DeleteExpired(before time.Time, limit int) (int64, error) {
+	res, err := s.deleteExpiredStmt.Exec(before, limit)
+	if err != nil {
+		return 0, errors.Wrap(err, "can't remove expired sessions")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "can't get affected rows")
+	}
+
+	return n, nil
+}
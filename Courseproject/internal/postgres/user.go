@@ -12,10 +12,18 @@ var _ user.Storage = &UserStorage{}
 type UserStorage struct {
 	statementStorage
 
-	createStmt      *sql.Stmt
-	findByEmailStmt *sql.Stmt
-	findByIDStmt    *sql.Stmt
-	updateByIDStmt  *sql.Stmt
+	createStmt           *sql.Stmt
+	findByEmailStmt      *sql.Stmt
+	findByIDStmt         *sql.Stmt
+	updateByIDStmt       *sql.Stmt
+	setEmailVerifiedStmt *sql.Stmt
+	updatePasswordStmt   *sql.Stmt
+	deleteTokensStmt     *sql.Stmt
+	createTokenStmt      *sql.Stmt
+	useTokenStmt         *sql.Stmt
+	searchStmt           *sql.Stmt
+	setRoleStmt          *sql.Stmt
+	setLockedStmt        *sql.Stmt
 }
 
 func NewUserStorage(db *DB) (*UserStorage, error) {
@@ -26,6 +34,14 @@ func NewUserStorage(db *DB) (*UserStorage, error) {
 		{Query: findUserByEmailQuery, Dst: &s.findByEmailStmt},
 		{Query: findUserByIDQuery, Dst: &s.findByIDStmt},
 		{Query: updateUserByIDQuery, Dst: &s.updateByIDStmt},
+		{Query: setEmailVerifiedQuery, Dst: &s.setEmailVerifiedStmt},
+		{Query: updatePasswordQuery, Dst: &s.updatePasswordStmt},
+		{Query: deleteUserTokensQuery, Dst: &s.deleteTokensStmt},
+		{Query: createUserTokenQuery, Dst: &s.createTokenStmt},
+		{Query: useUserTokenQuery, Dst: &s.useTokenStmt},
+		{Query: searchUsersQuery, Dst: &s.searchStmt},
+		{Query: setUserRoleQuery, Dst: &s.setRoleStmt},
+		{Query: setUserLockedQuery, Dst: &s.setLockedStmt},
 	}
 
 	if err := s.initStatements(stmts); err != nil {
@@ -35,10 +51,11 @@ func NewUserStorage(db *DB) (*UserStorage, error) {
 	return s, nil
 }
 
-const userFields = "id, first_name, last_name, birthday, email, password, created_at, updated_at"
+const userFields = "id, first_name, last_name, birthday, email, password, email_verified, role, locked, created_at, updated_at"
 
 func scanUser(scanner sqlScanner, u *user.User) error {
-	return scanner.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Birthday, &u.Email, &u.Password, &u.CreatedAt, &u.UpdatedAt)
+	return scanner.Scan(&u.ID, &u.FirstName, &u.LastName, &u.Birthday, &u.Email, &u.Password, &u.EmailVerified, &u.Role, &u.Locked,
+		&u.CreatedAt, &u.UpdatedAt)
 }
 
 const createUserQuery = "INSERT INTO users(first_name, last_name, birthday, email, password) VALUES ($1, $2, $3, $4, $5) RETURNING id"
@@ -79,10 +96,10 @@ func (s *UserStorage) FindByID(id int64) (*user.User, error) {
 	return &u, nil
 }
 
-const updateUserByIDQuery = "UPDATE users SET (first_name, last_name, birthday, email, password, updated_at) = ($1, $2, $3, $4, $5, now()) WHERE id=$6"
+const updateUserByIDQuery = "UPDATE users SET (first_name, last_name, birthday, email, password, email_verified, updated_at) = ($1, $2, $3, $4, $5, $6, now()) WHERE id=$7"
 
 func (s *UserStorage) UpdateByID(u *user.User) error {
-	_, err := s.updateByIDStmt.Exec(&u.FirstName, &u.LastName, &u.Birthday, &u.Email, &u.Password, &u.ID)
+	_, err := s.updateByIDStmt.Exec(&u.FirstName, &u.LastName, &u.Birthday, &u.Email, &u.Password, &u.EmailVerified, &u.ID)
 
 	if err != nil {
 		return errors.Wrap(err, "can't scan user")
@@ -90,3 +107,110 @@ func (s *UserStorage) UpdateByID(u *user.User) error {
 
 	return nil
 }
+
+const setEmailVerifiedQuery = "UPDATE users SET (email_verified, updated_at) = (true, now()) WHERE id=$1"
+
+func (s *UserStorage) SetEmailVerified(id int64) error {
+	if _, err := s.setEmailVerifiedStmt.Exec(id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const updatePasswordQuery = "UPDATE users SET (password, updated_at) = ($1, now()) WHERE id=$2"
+
+func (s *UserStorage) UpdatePassword(id int64, hash string) error {
+	if _, err := s.updatePasswordStmt.Exec(hash, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const deleteUserTokensQuery = "DELETE FROM user_tokens WHERE user_id=$1 AND purpose=$2"
+
+const createUserTokenQuery = "INSERT INTO user_tokens(token_hash, user_id, purpose, valid_until) VALUES ($1, $2, $3, $4)"
+
+func (s *UserStorage) CreateToken(t *user.Token) error {
+	tx, err := s.db.Session.Begin()
+	if err != nil {
+		return errors.Wrap(err, "can't begin transaction")
+	}
+
+	defer tx.Rollback() // nolint: errcheck
+
+	if _, err = tx.Stmt(s.deleteTokensStmt).Exec(t.UserID, t.Purpose); err != nil {
+		return errors.Wrap(err, "can't remove tokens")
+	}
+
+	if _, err = tx.Stmt(s.createTokenStmt).Exec(user.HashToken(t.Token), t.UserID, t.Purpose, t.ValidUntil); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return errors.Wrap(tx.Commit(), "can't commit transaction")
+}
+
+const useUserTokenQuery = "DELETE FROM user_tokens WHERE token_hash=$1 AND purpose=$2 AND valid_until > now() " +
+	"RETURNING user_id, purpose, valid_until"
+
+func (s *UserStorage) UseToken(token, purpose string) (*user.Token, error) {
+	t := user.Token{Token: token}
+
+	err := s.useTokenStmt.QueryRow(user.HashToken(token), purpose).Scan(&t.UserID, &t.Purpose, &t.ValidUntil)
+
+	switch {
+	case err == sql.ErrNoRows:
+		return nil, user.ErrWrongToken
+	case err != nil:
+		return nil, errors.Wrap(err, "can't exec query")
+	}
+
+	return &t, nil
+}
+
+const searchUsersQuery = "SELECT " + userFields + " FROM users " +
+	"WHERE strpos(lower(email || ' ' || first_name || ' ' || last_name), lower($1)) > 0 ORDER BY id LIMIT $2 OFFSET $3"
+
+func (s *UserStorage) Search(query string, limit, offset int) ([]*user.User, error) {
+	rows, err := s.searchStmt.Query(query, limit, offset)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't exec query")
+	}
+
+	defer rows.Close()
+
+	var users []*user.User
+
+	for rows.Next() {
+		u := new(user.User)
+
+		if err := scanUser(rows, u); err != nil {
+			return nil, errors.Wrap(err, "can't scan user")
+		}
+
+		users = append(users, u)
+	}
+
+	return users, errors.Wrap(rows.Err(), "can't read users")
+}
+
+const setUserRoleQuery = "UPDATE users SET (role, updated_at) = ($1, now()) WHERE id=$2"
+
+func (s *UserStorage) SetRole(id int64, role string) error {
+	if _, err := s.setRoleStmt.Exec(role, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
+
+const setUserLockedQuery = "UPDATE users SET (locked, updated_at) = ($1, now()) WHERE id=$2"
+
+func (s *UserStorage) SetLocked(id int64, locked bool) error {
+	if _, err := s.setLockedStmt.Exec(locked, id); err != nil {
+		return errors.Wrap(err, "can't exec query")
+	}
+
+	return nil
+}
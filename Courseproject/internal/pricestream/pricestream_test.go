@@ -0,0 +1,193 @@
+package pricestream
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"../calendar"
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const testCandles = "SBER,2019-01-30T07:00:00Z,213.8,214.14,213.1,213.17\n" +
+	"SBER,2019-01-30T07:05:00Z,213.17,213.8,213.17,213.75\n"
+
+// nolint: gomnd
+func Test_CSVSource(t *testing.T) {
+	r := require.New(t)
+
+	s, err := NewCSVSource(strings.NewReader(testCandles), Config{Speed: 1e6})
+	r.NoError(err)
+
+	_, err = s.Quotes(context.Background(), "AAPL")
+	r.Error(err)
+
+	quotes, err := s.Quotes(context.Background(), "SBER")
+	r.NoError(err)
+
+	var prices []float64
+	for q := range quotes {
+		prices = append(prices, q.BuyPrice)
+	}
+
+	r.Equal([]float64{213.8, 214.14, 213.1, 213.17, 213.17, 213.17, 213.8, 213.75}, prices)
+}
+
+// nolint: gomnd
+func Test_RandomSource(t *testing.T) {
+	r := require.New(t)
+	cfg := Config{Speed: 1, Seed: 42, StartPrice: 100, Volatility: 0.01, Spread: 0.001, Interval: time.Microsecond, Depth: 3, LevelSize: 10}
+
+	walk := func() []Quote {
+		s, err := NewRandomSource(cfg)
+		r.NoError(err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		quotes, err := s.Quotes(ctx, "SBER")
+		r.NoError(err)
+
+		var result []Quote
+		for i := 0; i < 10; i++ {
+			result = append(result, <-quotes)
+		}
+
+		return result
+	}
+
+	first, second := walk(), walk()
+	for i := range first {
+		r.Equal(first[i].BuyPrice, second[i].BuyPrice)
+		r.Equal(first[i].LastVolume, second[i].LastVolume)
+		r.True(first[i].BuyPrice >= first[i].SellPrice)
+
+		q := first[i]
+		r.Len(q.Asks, 3)
+		r.Len(q.Bids, 3)
+		r.Equal(Level{Price: q.BuyPrice, Size: 10}, q.Asks[0])
+		r.Equal(Level{Price: q.SellPrice, Size: 10}, q.Bids[0])
+		r.True(q.Asks[2].Price >= q.Asks[1].Price && q.Bids[2].Price <= q.Bids[1].Price)
+		r.Equal(int64(30), q.Asks[2].Size)
+		r.True(q.LastVolume >= 1 && q.LastVolume <= 10)
+	}
+}
+
+func newTestClient(t *testing.T, source Source) streamer.TradingServiceClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	streamer.RegisterTradingServiceServer(srv, NewServer(zap.NewNop().Sugar(), source, calendar.New(calendar.AlwaysOpen), 0))
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+		srv.Stop()
+	})
+
+	return streamer.NewTradingServiceClient(conn)
+}
+
+// nolint: gomnd
+func Test_Subscribe(t *testing.T) {
+	r := require.New(t)
+
+	source, err := NewRandomSource(Config{Speed: 1, Seed: 1, StartPrice: 100, Interval: time.Millisecond})
+	r.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := newTestClient(t, source).Subscribe(ctx)
+	r.NoError(err)
+
+	// tickers seen until every wanted one has come
+	receive := func(wanted ...string) map[string]bool {
+		seen := make(map[string]bool)
+
+		for {
+			price, err := stream.Recv()
+			r.NoError(err)
+
+			seen[price.Ticker] = true
+
+			done := true
+			for _, ticker := range wanted {
+				done = done && seen[ticker]
+			}
+
+			if done {
+				return seen
+			}
+		}
+	}
+
+	r.NoError(stream.Send(&streamer.SubscribeRequest{Add: []string{"SBER", "AAPL"}}))
+	receive("SBER", "AAPL")
+
+	r.NoError(stream.Send(&streamer.SubscribeRequest{Remove: []string{"SBER"}, Add: []string{"", "GAZP"}}))
+	receive("GAZP")
+
+	for i := 0; i < 50; i++ {
+		price, err := stream.Recv()
+		r.NoError(err)
+		r.Contains([]string{"AAPL", "GAZP"}, price.Ticker)
+	}
+}
+
+// nolint: gomnd
+func Test_Candles(t *testing.T) {
+	r := require.New(t)
+
+	source, err := NewCSVSource(strings.NewReader(testCandles), Config{Speed: 1e6, LevelSize: 5})
+	r.NoError(err)
+
+	client := newTestClient(t, source)
+
+	stream, err := client.Candles(context.Background(), &streamer.CandleRequest{Ticker: "SBER", Interval: 5})
+	r.NoError(err)
+
+	var result []*streamer.Candle
+
+	for {
+		c, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+
+		r.NoError(err)
+
+		result = append(result, c)
+	}
+
+	r.Len(result, 2)
+	r.Equal([]float64{213.8, 214.14, 213.1, 213.17}, []float64{result[0].Open, result[0].High, result[0].Low, result[0].Close})
+	r.Equal(int64(20), result[0].Volume)
+	r.Equal(213.75, result[1].Close)
+
+	ts, err := ptypes.Timestamp(result[1].Ts)
+	r.NoError(err)
+	r.Equal(time.Date(2019, 1, 30, 7, 5, 0, 0, time.UTC), ts)
+
+	bad, err := client.Candles(context.Background(), &streamer.CandleRequest{Ticker: "SBER"})
+	r.NoError(err)
+
+	_, err = bad.Recv()
+	r.Equal(codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,279 @@
+package pricestream
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"../calendar"
+	"../candles"
+	streamer "../streamer"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ streamer.TradingServiceServer = &Server{}
+
+// Server implements fintech.TradingService on top of a quotes source
+type Server struct {
+	logger    *zap.SugaredLogger
+	source    Source
+	calendar  *calendar.Calendar
+	dropAfter int
+}
+
+// NewServer creates the streamer building candles in sessions of the calendar,
+// dropAfter > 0 makes it break every stream after the number of quotes to check
+// reconnects of clients
+func NewServer(logger *zap.SugaredLogger, source Source, cal *calendar.Calendar, dropAfter int) *Server {
+	return &Server{logger: logger, source: source, calendar: cal, dropAfter: dropAfter}
+}
+
+func (s *Server) Price(req *streamer.PriceRequest, stream streamer.TradingService_PriceServer) error {
+	quotes, err := s.source.Quotes(stream.Context(), req.Ticker)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "can't stream %q: %v", req.Ticker, err)
+	}
+
+	s.logger.Infof("new subscriber for %s", req.Ticker)
+
+	sent := 0
+
+	for q := range quotes {
+		if s.dropAfter > 0 && sent == s.dropAfter {
+			s.logger.Infof("dropping subscriber for %s", req.Ticker)
+			return status.Error(codes.Unavailable, "stream is dropped")
+		}
+
+		resp, err := newPriceResponse(q)
+		if err != nil {
+			return status.Errorf(codes.Internal, "can't convert quote: %v", err)
+		}
+
+		if err := stream.Send(resp); err != nil {
+			return errors.Wrapf(err, "can't send price of %s", req.Ticker)
+		}
+
+		sent++
+	}
+
+	s.logger.Infof("subscriber for %s is gone", req.Ticker)
+
+	return stream.Context().Err()
+}
+
+// Subscribe multiplexes quotes of the tickers added to the stream, unknown tickers are skipped
+func (s *Server) Subscribe(stream streamer.TradingService_SubscribeServer) error {
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	requests, recvErrs := receiveRequests(ctx, stream)
+	quotes := make(chan Quote)
+	tickers := make(map[string]context.CancelFunc)
+	sent := 0
+
+	for {
+		select {
+		case req := <-requests:
+			s.changeTickers(ctx, tickers, req, quotes)
+		case err := <-recvErrs:
+			if err != io.EOF {
+				return errors.Wrap(err, "can't receive subscription")
+			}
+
+			// the client won't change tickers any more but still reads prices
+			recvErrs = nil
+		case q := <-quotes:
+			if _, ok := tickers[q.Ticker]; !ok {
+				continue
+			}
+
+			if s.dropAfter > 0 && sent == s.dropAfter {
+				s.logger.Info("dropping subscriber")
+				return status.Error(codes.Unavailable, "stream is dropped")
+			}
+
+			resp, err := newPriceResponse(q)
+			if err != nil {
+				return status.Errorf(codes.Internal, "can't convert quote: %v", err)
+			}
+
+			if err := stream.Send(resp); err != nil {
+				return errors.Wrapf(err, "can't send price of %s", q.Ticker)
+			}
+
+			sent++
+		case <-ctx.Done():
+			s.logger.Info("subscriber is gone")
+			return ctx.Err()
+		}
+	}
+}
+
+// changeTickers starts and stops quotes of the tickers of the stream forwarding them to out
+func (s *Server) changeTickers(ctx context.Context, tickers map[string]context.CancelFunc,
+	req *streamer.SubscribeRequest, out chan<- Quote) {
+	for _, ticker := range req.Remove {
+		if cancel, ok := tickers[ticker]; ok {
+			cancel()
+			delete(tickers, ticker)
+			s.logger.Infof("subscriber removed %s", ticker)
+		}
+	}
+
+	for _, ticker := range req.Add {
+		if _, ok := tickers[ticker]; ok {
+			continue
+		}
+
+		tickerCtx, cancel := context.WithCancel(ctx)
+
+		quotes, err := s.source.Quotes(tickerCtx, ticker)
+		if err != nil {
+			cancel()
+			s.logger.Warnf("can't stream %q: %v", ticker, err)
+
+			continue
+		}
+
+		tickers[ticker] = cancel
+		s.logger.Infof("subscriber added %s", ticker)
+
+		go forward(tickerCtx, quotes, out)
+	}
+}
+
+// receiveRequests reads ticker changes of the stream until it fails or ctx is done
+func receiveRequests(ctx context.Context, stream streamer.TradingService_SubscribeServer) (<-chan *streamer.SubscribeRequest, <-chan error) {
+	requests := make(chan *streamer.SubscribeRequest)
+	errs := make(chan error, 1)
+
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			select {
+			case requests <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return requests, errs
+}
+
+func forward(ctx context.Context, quotes <-chan Quote, out chan<- Quote) {
+	for q := range quotes {
+		if !send(ctx, out, q) {
+			return
+		}
+	}
+}
+
+// Candles aggregates the last trades of the quotes into candles of the interval
+// in sessions of the ticker exchange and sends every candle when it is finished
+func (s *Server) Candles(req *streamer.CandleRequest, stream streamer.TradingService_CandlesServer) error {
+	if req.Interval <= 0 {
+		return status.Error(codes.InvalidArgument, "interval must be positive")
+	}
+
+	quotes, err := s.source.Quotes(stream.Context(), req.Ticker)
+	if err != nil {
+		return status.Errorf(codes.NotFound, "can't stream %q: %v", req.Ticker, err)
+	}
+
+	s.logger.Infof("new candles subscriber for %s %dm", req.Ticker, req.Interval)
+
+	aggregator := candles.NewAggregator(time.Duration(req.Interval)*time.Minute, s.calendar.ForTicker(req.Ticker))
+
+	for q := range quotes {
+		if c, ok := aggregator.Add(newTrade(q)); ok {
+			if err := sendCandle(stream, c); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := stream.Context().Err(); err != nil {
+		return err
+	}
+
+	// the source is over, the last candle won't get more trades
+	for _, c := range aggregator.Flush() {
+		if err := sendCandle(stream, c); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// newTrade takes the last trade of the quote, quotes without trades are
+// taken at the middle of the spread
+// nolint: gomnd
+func newTrade(q Quote) candles.Trade {
+	price := q.LastPrice
+	if price == 0 {
+		price = (q.BuyPrice + q.SellPrice) / 2
+	}
+
+	return candles.Trade{Ticker: q.Ticker, Price: price, Volume: q.LastVolume, Time: q.Time}
+}
+
+func sendCandle(stream streamer.TradingService_CandlesServer, c candles.Candle) error {
+	ts, err := ptypes.TimestampProto(c.Time)
+	if err != nil {
+		return status.Errorf(codes.Internal, "can't convert candle time: %v", err)
+	}
+
+	err = stream.Send(&streamer.Candle{
+		Ticker: c.Ticker,
+		Ts:     ts,
+		Open:   c.Open,
+		High:   c.High,
+		Low:    c.Low,
+		Close:  c.Close,
+		Volume: c.Volume,
+	})
+
+	return errors.Wrapf(err, "can't send candle of %s", c.Ticker)
+}
+
+func newPriceResponse(q Quote) (*streamer.PriceResponse, error) {
+	ts, err := ptypes.TimestampProto(q.Time)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't convert time")
+	}
+
+	return &streamer.PriceResponse{
+		Ticker:     q.Ticker,
+		BuyPrice:   q.BuyPrice,
+		SellPrice:  q.SellPrice,
+		Ts:         ts,
+		Bids:       newPriceLevels(q.Bids),
+		Asks:       newPriceLevels(q.Asks),
+		LastPrice:  q.LastPrice,
+		LastVolume: q.LastVolume,
+	}, nil
+}
+
+func newPriceLevels(levels []Level) []*streamer.PriceLevel {
+	if len(levels) == 0 {
+		return nil
+	}
+
+	result := make([]*streamer.PriceLevel, len(levels))
+	for i, l := range levels {
+		result[i] = &streamer.PriceLevel{Price: l.Price, Size: l.Size}
+	}
+
+	return result
+}
@@ -0,0 +1,246 @@
+package pricestream
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/rand"
+	"time"
+
+	"../candles"
+	"github.com/pkg/errors"
+)
+
+// Quote is a pair of prices of the ticker at the moment with the order book around them
+// and the last trade
+type Quote struct {
+	Ticker     string
+	BuyPrice   float64
+	SellPrice  float64
+	Time       time.Time
+	Bids       []Level // from the best (highest) price, empty when the depth is unknown
+	Asks       []Level // from the best (lowest) price, empty when the depth is unknown
+	LastPrice  float64
+	LastVolume int64
+}
+
+// Level is the total size of orders at the price
+type Level struct {
+	Price float64
+	Size  int64
+}
+
+// Source produces quotes for the streamer
+type Source interface {
+	// Quotes streams quotes of the ticker until ctx is done or the source is exhausted
+	Quotes(ctx context.Context, ticker string) (<-chan Quote, error)
+}
+
+type Config struct {
+	Speed      float64       // playback speed, 1 means real time
+	Spread     float64       // relative difference between buy and sell prices
+	Loop       bool          // start the csv series again when it is over
+	Seed       int64         // seed of the random walk
+	StartPrice float64       // first price of the random walk
+	Volatility float64       // relative standard deviation of a random walk step
+	Interval   time.Duration // time between random walk steps
+	Depth      int           // number of order book levels on each side, 0 omits the book
+	LevelSize  int64         // size of the best levels, deeper levels are larger
+}
+
+var ErrUnknownTicker = errors.New("unknown ticker")
+
+// CSVSource replays candles going through open, low/high and close of every candle
+type CSVSource struct {
+	cfg     Config
+	candles []candles.Candle
+}
+
+func NewCSVSource(r io.Reader, cfg Config) (*CSVSource, error) {
+	if cfg.Speed <= 0 {
+		return nil, errors.New("speed must be positive")
+	}
+
+	data, err := candles.ReadCSV(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't load candles")
+	}
+
+	return &CSVSource{cfg: cfg, candles: data}, nil
+}
+
+func (s *CSVSource) Quotes(ctx context.Context, ticker string) (<-chan Quote, error) {
+	series := candles.ByTicker(s.candles, ticker)
+	if len(series) == 0 {
+		return nil, errors.Wrapf(ErrUnknownTicker, "no candles for %q", ticker)
+	}
+
+	interval := CandleInterval(series)
+	out := make(chan Quote)
+
+	go func() {
+		defer close(out)
+
+		for {
+			for _, c := range series {
+				quotes := CandleQuotes(c, interval, s.cfg)
+				step := interval / time.Duration(len(quotes))
+
+				for _, q := range quotes {
+					if !send(ctx, out, q) || !wait(ctx, scale(step, s.cfg.Speed)) {
+						return
+					}
+				}
+			}
+
+			if !s.cfg.Loop {
+				return
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// CandleQuotes spreads the path of the candle evenly over its interval,
+// every quote is a trade of the best level size
+func CandleQuotes(c candles.Candle, interval time.Duration, cfg Config) []Quote {
+	path := c.Path()
+	step := interval / time.Duration(len(path))
+	quotes := make([]Quote, len(path))
+
+	for i, price := range path {
+		quotes[i] = newQuote(c.Ticker, price, cfg.LevelSize, c.Time.Add(step*time.Duration(i)), cfg)
+	}
+
+	return quotes
+}
+
+// CandleInterval guesses the candle length as the smallest gap between candles
+func CandleInterval(series []candles.Candle) time.Duration {
+	interval := time.Duration(math.MaxInt64)
+
+	for i := 1; i < len(series); i++ {
+		if d := series[i].Time.Sub(series[i-1].Time); d > 0 && d < interval {
+			interval = d
+		}
+	}
+
+	if interval == time.Duration(math.MaxInt64) {
+		return time.Minute
+	}
+
+	return interval
+}
+
+// RandomSource produces a geometric random walk, the same for the same seed and ticker
+type RandomSource struct {
+	cfg Config
+}
+
+func NewRandomSource(cfg Config) (*RandomSource, error) {
+	if cfg.Speed <= 0 || cfg.Interval <= 0 || cfg.StartPrice <= 0 {
+		return nil, errors.New("speed, interval and start price must be positive")
+	}
+
+	return &RandomSource{cfg: cfg}, nil
+}
+
+func (s *RandomSource) Quotes(ctx context.Context, ticker string) (<-chan Quote, error) {
+	if ticker == "" {
+		return nil, errors.Wrap(ErrUnknownTicker, "empty ticker")
+	}
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(ticker))
+	rnd := rand.New(rand.NewSource(s.cfg.Seed ^ int64(h.Sum64()))) // nolint: gosec
+
+	out := make(chan Quote)
+
+	go func() {
+		defer close(out)
+
+		price := s.cfg.StartPrice
+
+		for {
+			volume := int64(0)
+			if s.cfg.LevelSize > 0 {
+				volume = 1 + rnd.Int63n(s.cfg.LevelSize)
+			}
+
+			if !send(ctx, out, newQuote(ticker, price, volume, time.Now(), s.cfg)) {
+				return
+			}
+
+			if !wait(ctx, scale(s.cfg.Interval, s.cfg.Speed)) {
+				return
+			}
+
+			price *= math.Exp(s.cfg.Volatility * rnd.NormFloat64())
+		}
+	}()
+
+	return out, nil
+}
+
+// newQuote builds the quote around the last trade price, book levels are
+// one spread apart and grow by the level size with every step from the best price
+// nolint: gomnd
+func newQuote(ticker string, price float64, volume int64, t time.Time, cfg Config) Quote {
+	q := Quote{
+		Ticker:     ticker,
+		BuyPrice:   round(price * (1 + cfg.Spread/2)),
+		SellPrice:  round(price * (1 - cfg.Spread/2)),
+		Time:       t,
+		LastPrice:  round(price),
+		LastVolume: volume,
+	}
+
+	if cfg.Depth <= 0 || cfg.LevelSize <= 0 {
+		return q
+	}
+
+	q.Bids = make([]Level, cfg.Depth)
+	q.Asks = make([]Level, cfg.Depth)
+
+	for i := range q.Asks {
+		step := float64(i) * cfg.Spread
+		size := cfg.LevelSize * int64(i+1)
+
+		q.Asks[i] = Level{Price: round(price * (1 + cfg.Spread/2 + step)), Size: size}
+		q.Bids[i] = Level{Price: round(price * (1 - cfg.Spread/2 - step)), Size: size}
+	}
+
+	return q
+}
+
+// nolint: gomnd
+func round(price float64) float64 {
+	return math.Round(price*100) / 100
+}
+
+func scale(d time.Duration, speed float64) time.Duration {
+	return time.Duration(float64(d) / speed)
+}
+
+func send(ctx context.Context, out chan<- Quote, q Quote) bool {
+	select {
+	case out <- q:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
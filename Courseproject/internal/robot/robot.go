@@ -1,32 +1,277 @@
 package robot
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"time"
 
 	"../../pkg/null"
+	"../order"
+	"github.com/pkg/errors"
 )
 
 type Robot struct {
-	RobotID       int64         `json:"robot_id"`
-	OwnerUserID   int64         `json:"owner_user_id"`
-	ParentRobotID int64         `json:"parent_robot_id"`
-	IsFavorite    bool          `json:"is_favorite"`
-	IsActive      bool          `json:"is_active"`
-	Ticker        string        `json:"ticker"`
-	BuyPrice      float64       `json:"buy_price"`
-	SellPrice     float64       `json:"sell_price"`
-	PlanStart     time.Time     `json:"plan_start"`
-	PlanEnd       time.Time     `json:"plan_end"`
-	PlanYield     float64       `json:"plan_yield"`
-	FactYield     float64       `json:"fact_yield"`
-	DealsCount    int64         `json:"deals_count"`
-	ActivatedAt   time.Time     `json:"activated_at"`
-	DeactivatedAt time.Time     `json:"deactivated_at"`
-	CreatedAt     time.Time     `json:"created_at"`
-	DeletedAt     null.NullTime `json:"deleted_at"`
+	RobotID          int64          `json:"robot_id"`
+	OwnerUserID      int64          `json:"owner_user_id"`
+	ParentRobotID    int64          `json:"parent_robot_id"`
+	IsFavorite       bool           `json:"is_favorite"`
+	IsActive         bool           `json:"is_active"`
+	Ticker           string         `json:"ticker"`
+	BuyPrice         float64        `json:"buy_price"`
+	SellPrice        float64        `json:"sell_price"`
+	PlanStart        time.Time      `json:"plan_start"`
+	PlanEnd          time.Time      `json:"plan_end"`
+	PlanYield        float64        `json:"plan_yield"`
+	FactYield        float64        `json:"fact_yield"`
+	RealizedPnL      float64        `json:"realized_pnl"`
+	UnrealizedPnL    float64        `json:"unrealized_pnl"`
+	DealsCount       int64          `json:"deals_count"`
+	ActivatedAt      time.Time      `json:"activated_at"`
+	DeactivatedAt    time.Time      `json:"deactivated_at"`
+	CreatedAt        time.Time      `json:"created_at"`
+	DeletedAt        null.NullTime  `json:"deleted_at"`
+	Strategy         string         `json:"strategy"`
+	StrategyParams   StrategyParams `json:"strategy_params"`
+	Status           string         `json:"status"`
+	FailedReconnects int64          `json:"failed_reconnects"`
+	LotSize          int64          `json:"lot_size"`
+	MaxPosition      int64          `json:"max_position"`
+	CommissionType   string         `json:"commission_type"`
+	Commission       float64        `json:"commission"`
+	Fees             float64        `json:"fees"`
+	StopLoss         float64        `json:"stop_loss"`
+	TakeProfit       float64        `json:"take_profit"`
+	MaxDrawdown      float64        `json:"max_drawdown"`
+	PeakYield        float64        `json:"peak_yield"`
+	StopReason       string         `json:"stop_reason"`
+}
+
+// Statuses of the robot in the background engine
+const (
+	StatusStopped      = "stopped"
+	StatusRunning      = "running"
+	StatusDisconnected = "disconnected"
+)
+
+// Reasons the engine stops the robot for, the limits are set in money
+// and a zero limit is not checked
+const (
+	StopReasonStopLoss    = "stop_loss"
+	StopReasonTakeProfit  = "take_profit"
+	StopReasonMaxDrawdown = "max_drawdown"
+)
+
+var ErrWrongRisk = errors.New("wrong risk limits")
+
+// ValidateRisk checks the stop-loss, take-profit and max drawdown of the robot
+func (r *Robot) ValidateRisk() error {
+	if r.StopLoss < 0 || r.TakeProfit < 0 || r.MaxDrawdown < 0 {
+		return errors.Wrap(ErrWrongRisk, "risk limits can't be negative")
+	}
+
+	return nil
+}
+
+// RiskBreach returns the reason to stop the robot marked with the position,
+// it is empty while the robot is within its limits. Stop-loss and take-profit
+// limit the unrealized PnL of the open position, max drawdown limits the fall
+// of the fact yield from its peak
+func (r *Robot) RiskBreach(pos Position) string {
+	switch {
+	case pos.Side == Bought && r.StopLoss > 0 && -r.UnrealizedPnL >= r.StopLoss:
+		return StopReasonStopLoss
+	case pos.Side == Bought && r.TakeProfit > 0 && r.UnrealizedPnL >= r.TakeProfit:
+		return StopReasonTakeProfit
+	case r.MaxDrawdown > 0 && r.PeakYield-r.FactYield >= r.MaxDrawdown:
+		return StopReasonMaxDrawdown
+	default:
+		return ""
+	}
+}
+
+// Commission models, percent is charged from the deal amount and fixed is
+// the same for every deal whatever its size
+const (
+	CommissionNone    = ""
+	CommissionPercent = "percent"
+	CommissionFixed   = "fixed"
+)
+
+var ErrWrongSizing = errors.New("wrong position sizing or commission")
+
+// ValidateSizing checks the lot size, the maximum position and the commission of the robot
+func (r *Robot) ValidateSizing() error {
+	switch {
+	case r.LotSize < 0 || r.MaxPosition < 0:
+		return errors.Wrap(ErrWrongSizing, "lot size and max position can't be negative")
+	case r.MaxPosition > 0 && r.MaxPosition < r.Lots():
+		return errors.Wrap(ErrWrongSizing, "max position is less than the lot size")
+	case r.Commission < 0:
+		return errors.Wrap(ErrWrongSizing, "commission can't be negative")
+	}
+
+	switch r.CommissionType {
+	case CommissionNone, CommissionPercent, CommissionFixed:
+		return nil
+	default:
+		return errors.Wrapf(ErrWrongSizing, "unknown commission type %q", r.CommissionType)
+	}
+}
+
+// Lots is the number of shares the robot buys at once, one when not set
+func (r *Robot) Lots() int64 {
+	if r.LotSize <= 0 {
+		return 1
+	}
+
+	return r.LotSize
+}
+
+// PositionLimit is the maximum number of shares the robot holds, one lot when not set
+func (r *Robot) PositionLimit() int64 {
+	if r.MaxPosition <= 0 {
+		return r.Lots()
+	}
+
+	return r.MaxPosition
+}
+
+// Fee is the commission of a deal of the quantity at the price
+func (r *Robot) Fee(price float64, quantity int64) float64 {
+	switch r.CommissionType {
+	case CommissionPercent:
+		return price * float64(quantity) * r.Commission / 100
+	case CommissionFixed:
+		return r.Commission
+	default:
+		return 0
+	}
+}
+
+// Capital is the cash the robot needs to buy its maximum position
+// at the buy price with the commissions of all its orders
+func (r *Robot) Capital() float64 {
+	limit := r.PositionLimit()
+
+	fee := r.Fee(r.BuyPrice, limit)
+	if r.CommissionType == CommissionFixed {
+		orders := (limit + r.Lots() - 1) / r.Lots()
+		fee = r.Commission * float64(orders)
+	}
+
+	return r.BuyPrice*float64(limit) + fee
+}
+
+// PlanDealYield is the yield of buying a lot at the buy price
+// and selling it at the sell price net of both commissions
+func (r *Robot) PlanDealYield() float64 {
+	lots := r.Lots()
+
+	return (r.SellPrice-r.BuyPrice)*float64(lots) - r.Fee(r.BuyPrice, lots) - r.Fee(r.SellPrice, lots)
+}
+
+// StrategyParams holds numeric parameters of the robot strategy, stored as jsonb
+type StrategyParams map[string]float64
+
+// Value implements driver.Valuer interface
+func (p StrategyParams) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("{}"), nil
+	}
+
+	return json.Marshal(p)
+}
+
+// Scan implements sql.Scanner interface
+func (p *StrategyParams) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	default:
+		return errors.Errorf("can't scan strategy params from %T", src)
+	}
+}
+
+// Side shows whether the robot holds shares or not
+type Side int
+
+const (
+	Sold Side = iota
+	Bought
+)
+
+// Position is the current state of the robot on the market
+type Position struct {
+	Side       Side      `json:"side"`
+	Quantity   int64     `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	EntryTime  time.Time `json:"entry_time"`
+}
+
+// Mark revalues the position at the price the robot could close it now net of
+// the closing commission, the fact yield is the realized PnL of closed deals
+// plus the unrealized one, its peak is kept for the max drawdown
+func (r *Robot) Mark(pos Position, price float64) {
+	r.UnrealizedPnL = pos.PnL(price)
+	if pos.Side == Bought {
+		r.UnrealizedPnL -= r.Fee(price, pos.Quantity)
+	}
+
+	r.FactYield = r.RealizedPnL + r.UnrealizedPnL
+
+	if r.FactYield > r.PeakYield {
+		r.PeakYield = r.FactYield
+	}
+}
+
+// Fill applies the execution to the position and the PnL of the robot, a sell
+// realizes (sell price - average buy price) * quantity, the rest stays unrealized.
+// The commission of every fill is charged from the realized PnL at once, Fill
+// returns it with the new position
+func (r *Robot) Fill(pos Position, f *order.Fill) (Position, float64) {
+	fee := r.Fee(f.Price, f.Quantity)
+	r.Fees += fee
+	r.RealizedPnL -= fee
+
+	switch f.Side {
+	case order.Buy:
+		if pos.Side == Sold {
+			r.DealsCount++
+			pos = Position{Side: Bought, EntryTime: f.FilledAt}
+		}
+
+		pos.EntryPrice = (pos.EntryPrice*float64(pos.Quantity) + f.Price*float64(f.Quantity)) / float64(pos.Quantity+f.Quantity)
+		pos.Quantity += f.Quantity
+	case order.Sell:
+		r.RealizedPnL += (f.Price - pos.EntryPrice) * float64(f.Quantity)
+		pos.Quantity -= f.Quantity
+
+		if pos.Quantity <= 0 {
+			pos = Position{Side: Sold}
+		}
+	}
+
+	r.Mark(pos, f.Price)
+
+	return pos, fee
+}
+
+// PnL is the unrealized profit of the position if it was closed at the price
+func (p Position) PnL(price float64) float64 {
+	if p.Side != Bought {
+		return 0
+	}
+
+	return (price - p.EntryPrice) * float64(p.Quantity)
 }
 
 type Storage interface {
+	// Create and UpdateByID derive the plan yield from the prices, the sizing and the commission
 	Create(r *Robot) error
 	GetAllRobots() ([]*Robot, error)
 	GetAllRobotsByOwnerID(id int64) ([]*Robot, error)
@@ -35,9 +280,24 @@ type Storage interface {
 	FindByID(id int64) (*Robot, error)
 	ActivateByID(id int64) error
 	DeactivateByID(id int64) error
+	// DeleteByID marks the robot deleted, RestoreByID brings it back to the catalog
 	DeleteByID(id int64) error
+	RestoreByID(id int64) error
 	UpdateByID(r *Robot) error
 	GetRobotsNeedToRun() ([]*Robot, error)
 	ActivateAllRobots() error
 	GetWorkingRobotsByTicker(ticker string) ([]*Robot, error)
+	// FindPositionByID returns Sold position for robots which have never traded
+	FindPositionByID(id int64) (*Position, error)
+	UpdatePositionByID(id int64, p *Position) error
+	UpdateStatusByID(id int64, status string, failedReconnects int64) error
+	// UpdatePnLByID stores the yield of the robot after a fill: the realized and
+	// unrealized PnL, the fees, the deals count, the fact yield and its peak
+	UpdatePnLByID(r *Robot) error
+	// UpdateUnrealizedPnLByID stores the mark-to-market PnL, the fact yield
+	// and its peak keeping the realized PnL stored before
+	UpdateUnrealizedPnLByID(id int64, pnl float64) error
+	// UpdateStopReasonByID records why the engine stopped the robot,
+	// activation of the robot clears the reason
+	UpdateStopReasonByID(id int64, reason string) error
 }
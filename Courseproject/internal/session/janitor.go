@@ -0,0 +1,72 @@
+package session
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// JanitorConfig sets how often expired sessions are purged and how many are deleted at once
+type JanitorConfig struct {
+	Interval  time.Duration
+	BatchSize int
+}
+
+// Janitor deletes expired sessions in batches so that purging never locks the whole table
+type Janitor struct {
+	logger  *zap.SugaredLogger
+	storage Storage
+	cfg     JanitorConfig
+}
+
+func NewJanitor(logger *zap.SugaredLogger, storage Storage, cfg JanitorConfig) (*Janitor, error) {
+	if cfg.Interval <= 0 || cfg.BatchSize <= 0 {
+		return nil, errors.Errorf("wrong janitor config %+v", cfg)
+	}
+
+	return &Janitor{logger: logger, storage: storage, cfg: cfg}, nil
+}
+
+// Run purges expired sessions every interval until ctx is done
+func (j *Janitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := j.Purge(ctx, time.Now()); err != nil {
+			j.logger.Errorf("can't purge expired sessions: %v", err)
+			janitorErrorsTotal.Inc()
+		} else if n > 0 {
+			j.logger.Infof("purged %d expired sessions", n)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Purge deletes sessions expired before now batch by batch and returns their number
+func (j *Janitor) Purge(ctx context.Context, now time.Time) (int64, error) {
+	var total int64
+
+	for ctx.Err() == nil {
+		n, err := j.storage.DeleteExpired(now, j.cfg.BatchSize)
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		sessionsPurgedTotal.Add(float64(n))
+
+		if n < int64(j.cfg.BatchSize) {
+			break
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,21 @@
+package session
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	sessionsPurgedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "fintech",
+		Subsystem: "sessions",
+		Name:      "purged_total",
+		Help:      "Expired sessions deleted by the janitor.",
+	})
+	janitorErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "fintech",
+		Subsystem: "sessions",
+		Name:      "purge_errors_total",
+		Help:      "Failed runs of the session janitor.",
+	})
+)
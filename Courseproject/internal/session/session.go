@@ -8,18 +8,37 @@ import (
 	"github.com/pkg/errors"
 )
 
+// Session keeps the refresh token of the user, every refresh rotates it to a new session
+// of the same family and keeps the old one rotated until it expires to detect its reuse.
+// The family is the login of a device, users see it as the id of their session
 type Session struct {
 	SessionID  string
 	UserID     int64
+	Family     string
+	Rotated    bool
+	UserAgent  string
+	IP         string
 	CreatedAt  time.Time
 	ValidUntil time.Time
 }
 
+var (
+	ErrTokenReused = errors.New("refresh token is reused")
+	ErrNoFamily    = errors.New("session family is empty")
+)
+
 type Storage interface {
 	Create(sess *Session) error
-	FindByID(id int64) (*Session, error)
 	FindByToken(token string) (*Session, error)
-	DeleteByID(id int64) error
+	// FindByUserID returns the valid sessions of the user which are not rotated, one per family
+	FindByUserID(userID int64) ([]*Session, error)
+	// Rotate marks the session of the token rotated and creates the next one,
+	// it returns ErrTokenReused if the session has already been rotated
+	Rotate(token string, next *Session) error
+	// DeleteFamily deletes all sessions of the family, it returns ErrNoFamily for the empty one
+	DeleteFamily(family string) error
+	// DeleteExpired deletes at most limit sessions expired before the time and returns their number
+	DeleteExpired(before time.Time, limit int) (int64, error)
 }
 
 const alphabet = "qwertyuiopasdfghjlzxcvbnmQWERTYUIOPASDFGHJKLZXCVBNM1234567890"
@@ -2,7 +2,9 @@ package session
 
 import (
 	"testing"
+	"time"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,3 +14,43 @@ func Test_GenerateToken(t *testing.T) {
 	r.NoError(err)
 	r.Equal(len(str), tokenLen)
 }
+
+// nolint: gomnd
+func Test_Signer(t *testing.T) {
+	r := require.New(t)
+
+	_, err := NewSigner(nil, time.Minute)
+	r.Error(err)
+
+	s, err := NewSigner([]byte("secret"), time.Minute)
+	r.NoError(err)
+
+	now := time.Now()
+
+	token, err := s.Sign(42, "admin", "family", now)
+	r.NoError(err)
+
+	claims, err := s.Verify(token)
+	r.NoError(err)
+	r.Equal(int64(42), claims.UserID)
+	r.Equal("42", claims.Subject)
+	r.Equal("family", claims.SessionID)
+	r.Equal("admin", claims.Role)
+
+	other, err := NewSigner([]byte("other"), time.Minute)
+	r.NoError(err)
+
+	_, err = other.Verify(token)
+	r.Equal(ErrInvalidToken, errors.Cause(err), "wrong key")
+
+	expired, err := s.Sign(42, "admin", "family", now.Add(-2*time.Minute))
+	r.NoError(err)
+
+	_, err = s.Verify(expired)
+	r.Equal(ErrInvalidToken, errors.Cause(err), "expired")
+
+	none := "eyJhbGciOiJub25lIiwidHlwIjoiSldUIn0.eyJ1aWQiOjQyfQ."
+
+	_, err = s.Verify(none)
+	r.Equal(ErrInvalidToken, errors.Cause(err), "unsigned")
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims of access tokens, the subject is the user id too
+type Claims struct {
+	UserID    int64  `json:"uid"`
+	Role      string `json:"role"`
+	SessionID string `json:"sid"` // the family of the refresh token
+	jwt.StandardClaims
+}
+
+// Signer issues short-lived access tokens signed by HMAC-SHA256 in JWT format,
+// services sharing the key verify them without the session storage
+type Signer struct {
+	key []byte
+	ttl time.Duration
+}
+
+func NewSigner(key []byte, ttl time.Duration) (*Signer, error) {
+	if len(key) == 0 {
+		return nil, errors.New("token key is required")
+	}
+
+	if ttl <= 0 {
+		return nil, errors.Errorf("wrong access token ttl %v", ttl)
+	}
+
+	return &Signer{key: key, ttl: ttl}, nil
+}
+
+// TTL returns the lifetime of access tokens
+func (s *Signer) TTL() time.Duration {
+	return s.ttl
+}
+
+// Sign issues the access token of the user session valid from now
+func (s *Signer) Sign(userID int64, role, sessionID string, now time.Time) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		Role:      role,
+		SessionID: sessionID,
+		StandardClaims: jwt.StandardClaims{
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(s.ttl).Unix(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.key)
+	if err != nil {
+		return "", errors.Wrap(err, "can't sign token")
+	}
+
+	return token, nil
+}
+
+// Verify checks the signature and the expiration of the access token
+func (s *Signer) Verify(token string) (*Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, errors.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+
+		return s.key, nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, err.Error())
+	}
+
+	if claims.ExpiresAt == 0 {
+		return nil, errors.Wrap(ErrInvalidToken, "token never expires")
+	}
+
+	return &claims, nil
+}
@@ -0,0 +1,76 @@
+package strategy
+
+import (
+	"../robot"
+	"github.com/pkg/errors"
+)
+
+const (
+	Threshold = "threshold"
+	Margin    = "margin"
+)
+
+// threshold buys when the quote falls to the robot buy price
+// and sells when it rises to the robot sell price
+type threshold struct {
+	buyPrice  float64
+	sellPrice float64
+}
+
+func newThreshold(r *robot.Robot) (Strategy, error) {
+	if r.BuyPrice <= 0 || r.BuyPrice >= r.SellPrice {
+		return nil, errors.New("buy price must be positive and less than sell price")
+	}
+
+	return &threshold{buyPrice: r.BuyPrice, sellPrice: r.SellPrice}, nil
+}
+
+func (s *threshold) Decide(pos robot.Position, tick Tick) Action {
+	switch pos.Side {
+	case robot.Sold:
+		if s.buyPrice >= tick.BuyPrice {
+			return Buy
+		}
+	case robot.Bought:
+		if s.sellPrice <= tick.SellPrice {
+			return Sell
+		}
+	}
+
+	return Hold
+}
+
+// margin buys at the robot buy price and sells as soon as the quote
+// is at least "margin" above the price the position was opened at
+type margin struct {
+	buyPrice float64
+	margin   float64
+}
+
+func newMargin(r *robot.Robot) (Strategy, error) {
+	m, ok := r.StrategyParams["margin"]
+	if !ok || m <= 0 {
+		return nil, errors.New("positive \"margin\" param is required")
+	}
+
+	if r.BuyPrice <= 0 {
+		return nil, errors.New("buy price must be positive")
+	}
+
+	return &margin{buyPrice: r.BuyPrice, margin: m}, nil
+}
+
+func (s *margin) Decide(pos robot.Position, tick Tick) Action {
+	switch pos.Side {
+	case robot.Sold:
+		if s.buyPrice >= tick.BuyPrice {
+			return Buy
+		}
+	case robot.Bought:
+		if tick.SellPrice-pos.EntryPrice >= s.margin {
+			return Sell
+		}
+	}
+
+	return Hold
+}
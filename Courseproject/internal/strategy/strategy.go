@@ -0,0 +1,177 @@
+package strategy
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"../order"
+	"../robot"
+	"github.com/pkg/errors"
+)
+
+// Action is a decision made by a strategy on a single tick
+type Action int
+
+const (
+	Hold Action = iota
+	Buy
+	Sell
+)
+
+func (a Action) String() string {
+	switch a {
+	case Buy:
+		return "buy"
+	case Sell:
+		return "sell"
+	default:
+		return "hold"
+	}
+}
+
+// Tick is a quote received from the price streamer
+type Tick struct {
+	Ticker     string
+	BuyPrice   float64
+	SellPrice  float64
+	Time       time.Time
+	Bids       []Level // from the best price, empty when the streamer sends no book
+	Asks       []Level
+	LastPrice  float64
+	LastVolume int64
+}
+
+// Level is the size available at the price of the order book
+type Level struct {
+	Price float64
+	Size  int64
+}
+
+// Available returns the size the order of the side can take at the limit price,
+// ok is false when the tick has no book and the size is unknown
+func (t Tick) Available(side string, price float64) (size int64, ok bool) {
+	levels := t.Asks
+	if side == order.Sell {
+		levels = t.Bids
+	}
+
+	for _, l := range levels {
+		if (side == order.Buy && l.Price > price) || (side == order.Sell && l.Price < price) {
+			break
+		}
+
+		size += l.Size
+	}
+
+	return size, len(levels) > 0
+}
+
+// Strategy decides what the robot should do with its position on every tick
+type Strategy interface {
+	Decide(pos robot.Position, tick Tick) Action
+}
+
+// NewOrder turns the decision of the strategy into the side, quantity and price
+// of the order to send, it returns nil when the robot has nothing to do.
+// The robot buys by its lot size and adds lots to the open position while
+// it is below the maximum one and the strategy would open it on the tick.
+// Orders never exceed the size the book of the tick has at their price
+func NewOrder(s Strategy, r *robot.Robot, pos robot.Position, tick Tick) *order.Order {
+	action := s.Decide(pos, tick)
+
+	switch {
+	case action == Sell && pos.Side == robot.Bought:
+		return limit(&order.Order{Side: order.Sell, Quantity: pos.Quantity, Price: tick.SellPrice}, tick)
+	case pos.Side == robot.Bought && action == Hold && pos.Quantity < r.PositionLimit():
+		action = s.Decide(robot.Position{Side: robot.Sold}, tick)
+	}
+
+	if action != Buy {
+		return nil
+	}
+
+	quantity := r.PositionLimit() - pos.Quantity
+	if lots := r.Lots(); quantity > lots {
+		quantity = lots
+	}
+
+	return limit(&order.Order{Side: order.Buy, Quantity: quantity, Price: tick.BuyPrice}, tick)
+}
+
+// limit cuts the order down to the available size, it returns nil for empty orders
+func limit(o *order.Order, tick Tick) *order.Order {
+	if size, ok := tick.Available(o.Side, o.Price); ok && o.Quantity > size {
+		o.Quantity = size
+	}
+
+	if o.Quantity <= 0 {
+		return nil
+	}
+
+	return o
+}
+
+// Factory builds a strategy for the robot using its strategy params
+type Factory func(r *robot.Robot) (Strategy, error)
+
+// Default is used for robots without explicitly chosen strategy
+const Default = Threshold
+
+var (
+	factories = make(map[string]Factory)
+	mutex     sync.RWMutex
+)
+
+var ErrUnknownStrategy = errors.New("unknown strategy")
+
+// Register makes a strategy available for robots by its name
+func Register(name string, factory Factory) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	factories[name] = factory
+}
+
+// New builds the strategy chosen for the robot
+func New(r *robot.Robot) (Strategy, error) {
+	name := r.Strategy
+	if name == "" {
+		name = Default
+	}
+
+	mutex.RLock()
+	factory, ok := factories[name]
+	mutex.RUnlock()
+
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownStrategy, "strategy %q", name)
+	}
+
+	s, err := factory(r)
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't create strategy %q", name)
+	}
+
+	return s, nil
+}
+
+// Names returns sorted names of all registered strategies
+func Names() []string {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func init() {
+	Register(Threshold, newThreshold)
+	Register(Margin, newMargin)
+}
@@ -0,0 +1,104 @@
+package strategy
+
+import (
+	"testing"
+
+	"../order"
+	"../robot"
+	"github.com/stretchr/testify/require"
+)
+
+// nolint: gomnd
+func Test_Threshold(t *testing.T) {
+	r := require.New(t)
+
+	s, err := New(&robot.Robot{BuyPrice: 100, SellPrice: 110})
+	r.NoError(err)
+
+	r.Equal(Hold, s.Decide(robot.Position{Side: robot.Sold}, Tick{BuyPrice: 101, SellPrice: 100}))
+	r.Equal(Buy, s.Decide(robot.Position{Side: robot.Sold}, Tick{BuyPrice: 100, SellPrice: 99}))
+	r.Equal(Hold, s.Decide(robot.Position{Side: robot.Bought}, Tick{BuyPrice: 110, SellPrice: 109}))
+	r.Equal(Sell, s.Decide(robot.Position{Side: robot.Bought}, Tick{BuyPrice: 111, SellPrice: 110}))
+}
+
+// nolint: gomnd
+func Test_Margin(t *testing.T) {
+	r := require.New(t)
+
+	_, err := New(&robot.Robot{Strategy: Margin, BuyPrice: 100})
+	r.Error(err)
+
+	s, err := New(&robot.Robot{Strategy: Margin, BuyPrice: 100, StrategyParams: robot.StrategyParams{"margin": 5}})
+	r.NoError(err)
+
+	pos := robot.Position{Side: robot.Bought, EntryPrice: 98}
+	r.Equal(Hold, s.Decide(pos, Tick{SellPrice: 102}))
+	r.Equal(Sell, s.Decide(pos, Tick{SellPrice: 103}))
+}
+
+func Test_UnknownStrategy(t *testing.T) {
+	r := require.New(t)
+
+	_, err := New(&robot.Robot{Strategy: "unknown"})
+	r.Error(err)
+	r.Contains(Names(), Threshold)
+}
+
+// nolint: gomnd
+func Test_NewOrder(t *testing.T) {
+	r := require.New(t)
+
+	rb := &robot.Robot{BuyPrice: 100, SellPrice: 110, LotSize: 3, MaxPosition: 5}
+	s, err := New(rb)
+	r.NoError(err)
+
+	o := NewOrder(s, rb, robot.Position{Side: robot.Sold}, Tick{BuyPrice: 100, SellPrice: 99})
+	r.Equal(order.Buy, o.Side)
+	r.Equal(int64(3), o.Quantity)
+
+	o = NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 3}, Tick{BuyPrice: 100, SellPrice: 99})
+	r.Equal(int64(2), o.Quantity, "the position can't exceed the max one")
+
+	r.Nil(NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 5}, Tick{BuyPrice: 100, SellPrice: 99}))
+	r.Nil(NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 3}, Tick{BuyPrice: 105, SellPrice: 104}))
+
+	o = NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 5}, Tick{BuyPrice: 111, SellPrice: 110})
+	r.Equal(order.Sell, o.Side)
+	r.Equal(int64(5), o.Quantity)
+}
+
+// nolint: gomnd
+func Test_NewOrderDepth(t *testing.T) {
+	r := require.New(t)
+
+	rb := &robot.Robot{BuyPrice: 100, SellPrice: 110, LotSize: 5, MaxPosition: 5}
+	s, err := New(rb)
+	r.NoError(err)
+
+	tick := Tick{
+		BuyPrice:  100,
+		SellPrice: 99,
+		Asks:      []Level{{Price: 100, Size: 2}, {Price: 100.5, Size: 10}},
+		Bids:      []Level{{Price: 99, Size: 1}},
+	}
+
+	size, ok := tick.Available(order.Buy, 100.5)
+	r.True(ok)
+	r.Equal(int64(12), size)
+
+	_, ok = Tick{}.Available(order.Buy, 100)
+	r.False(ok, "ticks without book don't limit orders")
+
+	o := NewOrder(s, rb, robot.Position{Side: robot.Sold}, tick)
+	r.Equal(int64(2), o.Quantity, "only the best ask is at the robot price")
+
+	tick.BuyPrice, tick.SellPrice = 111, 110
+	tick.Bids = []Level{{Price: 110, Size: 3}, {Price: 109, Size: 10}}
+
+	o = NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 5}, tick)
+	r.Equal(order.Sell, o.Side)
+	r.Equal(int64(3), o.Quantity, "the rest of the position is sold on the next ticks")
+
+	tick.Bids = []Level{{Price: 109, Size: 10}}
+	r.Nil(NewOrder(s, rb, robot.Position{Side: robot.Bought, Quantity: 5}, tick))
+}
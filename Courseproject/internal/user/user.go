@@ -1,8 +1,11 @@
 package user
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
+	"../account"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -14,15 +17,63 @@ type User struct {
 	Birthday  time.Time `json:"birthday"`
 	Email     string    `json:"email"`
 	Password  string    `json:"password"`
+	// EmailVerified is set by the link sent to the email, unverified users can't activate robots
+	EmailVerified bool   `json:"email_verified"`
+	Role          string `json:"role"`
+	// Locked users can't sign in or refresh their tokens
+	Locked    bool `json:"locked"`
 	CreatedAt time.Time
 	UpdatedAt time.Time
 }
 
+// Roles of users, admins moderate users and robots
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+var ErrWrongRole = errors.New("wrong role")
+
+// CheckRole validates the role set by an admin
+func CheckRole(role string) error {
+	if role != RoleUser && role != RoleAdmin {
+		return errors.Wrapf(ErrWrongRole, "role %q", role)
+	}
+
+	return nil
+}
+
 type ShortUser struct {
 	FirstName string    `json:"first_name"`
 	LastName  string    `json:"last_name"`
 	Email     string    `json:"email"`
 	Birthday  time.Time `json:"birthday"`
+	// EmailVerified and Account are shown to the user only
+	EmailVerified bool             `json:"email_verified,omitempty"`
+	Account       *account.Account `json:"account,omitempty"`
+}
+
+// Token is a one-time token sent to the email of the user
+type Token struct {
+	Token      string
+	UserID     int64
+	Purpose    string
+	ValidUntil time.Time
+}
+
+// Purposes of tokens
+const (
+	VerifyEmail   = "verify_email"
+	ResetPassword = "reset_password"
+)
+
+var ErrWrongToken = errors.New("wrong or expired token")
+
+// HashToken is the SHA-256 of the token kept by storages instead of the token itself,
+// so the database doesn't leak tokens valid for resetting passwords
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
 type Storage interface {
@@ -30,6 +81,16 @@ type Storage interface {
 	FindByEmail(email string) (*User, error)
 	FindByID(id int64) (*User, error)
 	UpdateByID(*User) error
+	SetEmailVerified(id int64) error
+	UpdatePassword(id int64, hash string) error
+	// CreateToken stores the hash of the token replacing earlier tokens of the user with the same purpose
+	CreateToken(t *Token) error
+	// UseToken deletes the valid token of the purpose and returns it, ErrWrongToken otherwise
+	UseToken(token, purpose string) (*Token, error)
+	// Search finds users whose email or name contains the query, all users for the empty one
+	Search(query string, limit, offset int) ([]*User, error)
+	SetRole(id int64, role string) error
+	SetLocked(id int64, locked bool) error
 }
 
 func (u *User) CheckCorrectData() bool {
@@ -29,3 +29,12 @@ func Test_CheckPasswordHash(t *testing.T) {
 
 	r.Equal(CheckPasswordHash(tc, hashedPass), true)
 }
+
+func Test_HashToken(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(HashToken("token"), HashToken("token"))
+	r.NotEqual(HashToken("token"), HashToken("other"))
+	r.NotContains(HashToken("token"), "token")
+	r.Len(HashToken("token"), 64)
+}
@@ -0,0 +1,3 @@
+ALTER TABLE robots
+    ADD COLUMN strategy        TEXT  NOT NULL DEFAULT '',
+    ADD COLUMN strategy_params JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,8 @@
+CREATE TABLE robot_positions
+(
+    robot_id    BIGINT PRIMARY KEY REFERENCES robots (robot_id),
+    side        SMALLINT         NOT NULL DEFAULT 0,
+    entry_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    entry_time  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
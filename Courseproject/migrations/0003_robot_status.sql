@@ -0,0 +1,3 @@
+ALTER TABLE robots
+    ADD COLUMN status            TEXT   NOT NULL DEFAULT 'stopped',
+    ADD COLUMN failed_reconnects BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1,35 @@
+CREATE TABLE orders
+(
+    order_id        TEXT PRIMARY KEY,
+    robot_id        BIGINT           NOT NULL REFERENCES robots (robot_id),
+    ticker          TEXT             NOT NULL,
+    side            TEXT             NOT NULL,
+    quantity        BIGINT           NOT NULL,
+    price           DOUBLE PRECISION NOT NULL,
+    filled_quantity BIGINT           NOT NULL DEFAULT 0,
+    status          TEXT             NOT NULL,
+    reject_reason   TEXT             NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ      NOT NULL DEFAULT now(),
+    updated_at      TIMESTAMPTZ      NOT NULL DEFAULT now()
+);
+
+CREATE INDEX orders_robot_id_idx ON orders (robot_id, status);
+
+CREATE TABLE fills
+(
+    fill_id   BIGSERIAL PRIMARY KEY,
+    order_id  TEXT             NOT NULL REFERENCES orders (order_id),
+    robot_id  BIGINT           NOT NULL REFERENCES robots (robot_id),
+    ticker    TEXT             NOT NULL,
+    side      TEXT             NOT NULL,
+    quantity  BIGINT           NOT NULL,
+    price     DOUBLE PRECISION NOT NULL,
+    filled_at TIMESTAMPTZ      NOT NULL
+);
+
+CREATE INDEX fills_order_id_idx ON fills (order_id);
+
+ALTER TABLE robot_positions
+    ADD COLUMN quantity BIGINT NOT NULL DEFAULT 0;
+
+UPDATE robot_positions SET quantity = 1 WHERE side = 1;
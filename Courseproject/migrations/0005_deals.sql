@@ -0,0 +1,5 @@
+ALTER TABLE orders
+    ADD COLUMN quote_time TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- deals of a robot are its fills
+CREATE INDEX fills_robot_id_idx ON fills (robot_id, fill_id);
@@ -0,0 +1,11 @@
+ALTER TABLE robots
+    ADD COLUMN realized_pnl   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN unrealized_pnl DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- the old fact yield is the sum of sells minus the sum of buys,
+-- the cost of the open position is not lost but held in shares
+UPDATE robots r
+SET realized_pnl = r.fact_yield + COALESCE(
+        (SELECT p.entry_price * p.quantity FROM robot_positions p WHERE p.robot_id = r.robot_id AND p.side = 1), 0);
+
+UPDATE robots SET fact_yield = realized_pnl;
@@ -0,0 +1,18 @@
+ALTER TABLE robots
+    ADD COLUMN lot_size        BIGINT           NOT NULL DEFAULT 1,
+    ADD COLUMN max_position    BIGINT           NOT NULL DEFAULT 0,
+    ADD COLUMN commission_type TEXT             NOT NULL DEFAULT '',
+    ADD COLUMN commission      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN fees            DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+ALTER TABLE fills
+    ADD COLUMN fee DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+-- the plan yield of a deal of one lot net of the buy and sell commissions,
+-- robots created before commissions kept the yield sent by their clients
+UPDATE robots
+SET plan_yield = (sell_price - buy_price) * lot_size - CASE commission_type
+    WHEN 'percent' THEN (buy_price + sell_price) * lot_size * commission / 100
+    WHEN 'fixed' THEN 2 * commission
+    ELSE 0
+    END;
@@ -0,0 +1,8 @@
+ALTER TABLE robots
+    ADD COLUMN stop_loss    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN take_profit  DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN max_drawdown DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN peak_yield   DOUBLE PRECISION NOT NULL DEFAULT 0,
+    ADD COLUMN stop_reason  TEXT             NOT NULL DEFAULT '';
+
+UPDATE robots SET peak_yield = GREATEST(fact_yield, 0);
@@ -0,0 +1,17 @@
+-- money is kept in whole kopecks
+CREATE TABLE accounts
+(
+    user_id    BIGINT PRIMARY KEY REFERENCES users (id),
+    balance    NUMERIC(20, 2) NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ    NOT NULL DEFAULT now()
+);
+
+-- the capital reserved by active robots for their positions
+CREATE TABLE robot_reservations
+(
+    robot_id BIGINT PRIMARY KEY REFERENCES robots (robot_id),
+    user_id  BIGINT         NOT NULL REFERENCES users (id),
+    amount   NUMERIC(20, 2) NOT NULL
+);
+
+CREATE INDEX robot_reservations_user_id_idx ON robot_reservations (user_id);
@@ -0,0 +1,14 @@
+-- sessions keep refresh tokens, a refresh rotates the token to a new session of the
+-- same family and the rotated one stays until it expires to detect its reuse
+ALTER TABLE session DROP CONSTRAINT IF EXISTS session_pkey;
+ALTER TABLE session ADD PRIMARY KEY (session_id);
+
+ALTER TABLE session
+    ADD COLUMN family  TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN rotated BOOLEAN NOT NULL DEFAULT false;
+
+-- every existing session becomes a family of its own
+UPDATE session SET family = session_id WHERE family = '';
+
+CREATE INDEX session_user_id_idx ON session (user_id);
+CREATE INDEX session_family_idx ON session (family);
@@ -0,0 +1,4 @@
+-- users keep a session per device, the client is shown in the list of sessions
+ALTER TABLE session
+    ADD COLUMN user_agent TEXT NOT NULL DEFAULT '',
+    ADD COLUMN ip         TEXT NOT NULL DEFAULT '';
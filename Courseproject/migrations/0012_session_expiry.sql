@@ -0,0 +1,4 @@
+-- the lifetime of sessions is set by auth-api, expired ones are purged by its janitor
+ALTER TABLE session ALTER COLUMN valid_until DROP DEFAULT;
+
+CREATE INDEX session_valid_until_idx ON session (valid_until);
@@ -0,0 +1,15 @@
+-- users registered before the verification keep activating robots
+ALTER TABLE users ADD COLUMN email_verified BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE users ALTER COLUMN email_verified SET DEFAULT false;
+
+-- one-time tokens sent by email to verify it and to reset the password,
+-- only SHA-256 hashes of the tokens are stored
+CREATE TABLE user_tokens
+(
+    token_hash  TEXT PRIMARY KEY,
+    user_id     BIGINT      NOT NULL REFERENCES users (id),
+    purpose     TEXT        NOT NULL,
+    valid_until TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX user_tokens_user_id_idx ON user_tokens (user_id, purpose);
@@ -0,0 +1,5 @@
+-- admins moderate users and robots, the first admin is appointed by
+-- UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN role   TEXT    NOT NULL DEFAULT 'user',
+    ADD COLUMN locked BOOLEAN NOT NULL DEFAULT false;